@@ -1,14 +1,11 @@
 import { Chessboard } from 'react-chessboard';
 import { Chess } from 'chess.js';
 import { useRef, useState, useEffect } from 'react';
-import { useAuth } from '../context/AuthContext';
 import gameSocket from '../services/gameSocket';
 import { getBotMove } from '../utils/botRunner';
-import { updateRatings } from '../services/api';
 import './ChessBoard.css';
 
 function ChessBoard({ gameData, onGameEnd, botCode }) {
-  const { user } = useAuth();
   const chessGameRef = useRef(new Chess());
   const chessGame = chessGameRef.current;
   const [chessPosition, setChessPosition] = useState(chessGame.fen());
@@ -24,12 +21,7 @@ function ChessBoard({ gameData, onGameEnd, botCode }) {
   useEffect(() => {
     const connectToGame = async () => {
       try {
-        await gameSocket.connect(
-          gameData.wsPort,
-          gameData.gameId,
-          user.id,
-          gameData.playerColor
-        );
+        await gameSocket.connect(gameData.gameId);
         setConnectionStatus('connected');
 
         // Set up message handlers
@@ -91,7 +83,7 @@ function ChessBoard({ gameData, onGameEnd, botCode }) {
     return () => {
       gameSocket.disconnect();
     };
-  }, [gameData, user.id]);
+  }, [gameData]);
 
   // Bot automation: automatically make moves when it's our turn
   useEffect(() => {
@@ -142,41 +134,12 @@ function ChessBoard({ gameData, onGameEnd, botCode }) {
     return () => clearInterval(interval);
   }, [chessPosition, currentTurn, gameData.playerColor, botCode, gameOver]);
 
-  const handleGameOver = async (result) => {
+  const handleGameOver = (result) => {
     setGameOver(true);
     setGameResult(result);
 
-    // Only update ratings if we have valid game data with model IDs
+    // Ratings are updated by the server when it records the result
     console.log('Game over with result:', result);
-    console.log('Game data:', gameData);
-
-    if (gameData.modelId && gameData.opponentModelId) {
-      try {
-        // Determine winner and loser based on game result
-        let winnerId, loserId, isDraw = false;
-
-        if (result.winner === 'draw') {
-          // For draws, order doesn't matter, but we still need both IDs
-          isDraw = true;
-          winnerId = gameData.modelId;
-          loserId = gameData.opponentModelId;
-        } else if (result.winner === gameData.playerColor) {
-          // Our model won
-          winnerId = gameData.modelId;
-          loserId = gameData.opponentModelId;
-        } else {
-          // Opponent model won
-          winnerId = gameData.opponentModelId;
-          loserId = gameData.modelId;
-        }
-
-        console.log(`Updating ratings: ${winnerId} vs ${loserId}, isDraw: ${isDraw}`);
-        await updateRatings(winnerId, loserId, isDraw);
-        console.log('Ratings updated successfully');
-      } catch (error) {
-        console.error('Failed to update ratings:', error);
-      }
-    }
 
     setTimeout(() => {
       onGameEnd();
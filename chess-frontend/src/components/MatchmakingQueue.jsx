@@ -1,12 +1,12 @@
-import { useState, useEffect } from 'react';
-import { joinMatchmakingQueue, getMatchmakingStatus, leaveMatchmakingQueue } from '../services/api';
+import { useState, useEffect, useRef } from 'react';
+import { joinMatchmakingQueue, leaveMatchmakingQueue, openMatchmakingEvents } from '../services/api';
 import './MatchmakingQueue.css';
 
 function MatchmakingQueue({ modelId, onMatchFound, onCancel }) {
   const [status, setStatus] = useState('joining'); // joining, queued, matched
   const [queuePosition, setQueuePosition] = useState(null);
   const [error, setError] = useState('');
-  const [polling, setPolling] = useState(null);
+  const eventsRef = useRef(null);
 
   useEffect(() => {
     const joinQueue = async () => {
@@ -16,7 +16,7 @@ function MatchmakingQueue({ modelId, onMatchFound, onCancel }) {
           handleMatchFound(response);
         } else {
           setStatus('queued');
-          startPolling();
+          listenForEvents();
         }
       } catch (err) {
         setError('Failed to join queue: ' + (err.response?.data?.error || err.message));
@@ -27,44 +27,57 @@ function MatchmakingQueue({ modelId, onMatchFound, onCancel }) {
     joinQueue();
 
     return () => {
-      if (polling) {
-        clearInterval(polling);
-      }
+      closeEvents();
     };
   }, [modelId]);
 
-  const startPolling = () => {
-    const interval = setInterval(async () => {
-      try {
-        const response = await getMatchmakingStatus();
+  const closeEvents = () => {
+    if (eventsRef.current) {
+      eventsRef.current.close();
+      eventsRef.current = null;
+    }
+  };
 
-        if (response.status === 'matched') {
-          clearInterval(interval);
-          handleMatchFound(response);
-        } else if (response.status === 'queued') {
-          setQueuePosition(response.queuePosition);
-        } else if (response.status === 'not_queued') {
-          clearInterval(interval);
-          setError('You are no longer in the queue');
-          setStatus('error');
-        }
-      } catch (err) {
-        console.error('Polling error:', err);
-      }
-    }, 2000); // Poll every 2 seconds
+  const listenForEvents = () => {
+    closeEvents();
+    const events = openMatchmakingEvents();
+    eventsRef.current = events;
+
+    events.addEventListener('queue_position', (event) => {
+      const data = JSON.parse(event.data);
+      setQueuePosition(data.queuePosition);
+    });
+
+    events.addEventListener('match_found', (event) => {
+      closeEvents();
+      handleMatchFound(JSON.parse(event.data));
+    });
+
+    const handleRemoved = () => {
+      closeEvents();
+      setError('You are no longer in the queue');
+      setStatus('error');
+    };
+    events.addEventListener('match_cancelled', handleRemoved);
+    events.addEventListener('not_queued', handleRemoved);
+    events.addEventListener('queue_closed', (event) => {
+      closeEvents();
+      setError(JSON.parse(event.data).reason || 'The matchmaking queue was closed');
+      setStatus('error');
+    });
 
-    setPolling(interval);
+    events.onerror = (err) => {
+      // EventSource reconnects on its own; the server replays the current state
+      console.error('Matchmaking event stream error:', err);
+    };
   };
 
   const handleMatchFound = (matchData) => {
     setStatus('matched');
-    if (polling) {
-      clearInterval(polling);
-    }
+    closeEvents();
     setTimeout(() => {
       onMatchFound({
         gameId: matchData.gameId,
-        wsPort: matchData.wsPort,
         playerColor: matchData.playerColor,
         // Include the opponent model ID if it's provided by the server
         opponentModelId: matchData.opponentModelId || null
@@ -74,9 +87,7 @@ function MatchmakingQueue({ modelId, onMatchFound, onCancel }) {
 
   const handleLeaveQueue = async () => {
     try {
-      if (polling) {
-        clearInterval(polling);
-      }
+      closeEvents();
       await leaveMatchmakingQueue();
       onCancel();
     } catch (err) {
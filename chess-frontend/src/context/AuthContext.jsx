@@ -1,5 +1,5 @@
 import { createContext, useContext, useState, useEffect } from 'react';
-import { login as apiLogin, register as apiRegister } from '../services/api';
+import { login as apiLogin, register as apiRegister, logout as apiLogout } from '../services/api';
 
 const AuthContext = createContext(null);
 
@@ -28,14 +28,19 @@ export const AuthProvider = ({ children }) => {
     setLoading(false);
   }, []);
 
+  const storeSession = (response) => {
+    setToken(response.token);
+    setUser(response.user);
+    localStorage.setItem('token', response.token);
+    localStorage.setItem('refreshToken', response.refresh_token);
+    localStorage.setItem('user', JSON.stringify(response.user));
+  };
+
   const login = async (email, password) => {
     try {
       const response = await apiLogin(email, password);
       if (response.token && response.user) {
-        setToken(response.token);
-        setUser(response.user);
-        localStorage.setItem('token', response.token);
-        localStorage.setItem('user', JSON.stringify(response.user));
+        storeSession(response);
         return { success: true };
       }
       return { success: false, error: 'Invalid response from server' };
@@ -51,10 +56,7 @@ export const AuthProvider = ({ children }) => {
     try {
       const response = await apiRegister(username, email, password);
       if (response.token && response.user) {
-        setToken(response.token);
-        setUser(response.user);
-        localStorage.setItem('token', response.token);
-        localStorage.setItem('user', JSON.stringify(response.user));
+        storeSession(response);
         return { success: true };
       }
       return { success: false, error: 'Invalid response from server' };
@@ -67,9 +69,16 @@ export const AuthProvider = ({ children }) => {
   };
 
   const logout = () => {
+    // Revoke the session server-side; local state is cleared regardless
+    const refreshToken = localStorage.getItem('refreshToken');
+    if (refreshToken) {
+      apiLogout(refreshToken).catch(() => {});
+    }
+
     setUser(null);
     setToken(null);
     localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
     localStorage.removeItem('user');
   };
 
@@ -18,6 +18,42 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+// Exchanges the stored refresh token for a new token pair. Concurrent
+// callers share one request, as each refresh token can only be used once.
+let refreshing = null;
+export const refreshSession = () => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refreshToken');
+    refreshing = axios
+      .post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken })
+      .then((response) => {
+        localStorage.setItem('token', response.data.token);
+        localStorage.setItem('refreshToken', response.data.refresh_token);
+        return response.data.token;
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
+// Retry requests rejected for an expired access token once after refreshing
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const config = error.config;
+    if (error.response?.status !== 401 || config._retried || !localStorage.getItem('refreshToken')) {
+      throw error;
+    }
+
+    config._retried = true;
+    const token = await refreshSession();
+    config.headers.Authorization = `Bearer ${token}`;
+    return api(config);
+  }
+);
+
 // Auth APIs
 export const register = async (username, email, password) => {
   const response = await api.post('/auth/register', { username, email, password });
@@ -29,6 +65,11 @@ export const login = async (email, password) => {
   return response.data;
 };
 
+export const logout = async (refreshToken) => {
+  const response = await api.post('/auth/logout', { refresh_token: refreshToken });
+  return response.data;
+};
+
 // Models APIs
 export const getUserModels = async (userId) => {
   const response = await api.get(`/models/user/${userId}`);
@@ -71,14 +112,12 @@ export const leaveMatchmakingQueue = async () => {
   return response.data;
 };
 
-// Rating APIs
-export const updateRatings = async (winnerId, loserId, isDraw = false) => {
-  const response = await api.put('/models/rating', {
-    winner_id: winnerId,
-    loser_id: loserId,
-    is_draw: isDraw
-  });
-  return response.data;
+// Opens a server-sent event stream of queue and match updates
+export const openMatchmakingEvents = () => {
+  const token = localStorage.getItem('token');
+  return new EventSource(
+    `${API_BASE_URL}/matchmaking/events?access_token=${encodeURIComponent(token)}`
+  );
 };
 
 // Local Python execution using Pyodide
@@ -1,4 +1,7 @@
-const ENGINE_HOST = import.meta.env.VITE_ENGINE_HOST || 'localhost';
+const SERVER_URL = import.meta.env.VITE_SERVER_URL || 'http://localhost:8080';
+
+// Games are reached through the server's WebSocket gateway rather than the engine directly
+const GATEWAY_URL = SERVER_URL.replace(/^http/, 'ws');
 
 class GameSocket {
   constructor() {
@@ -16,11 +19,12 @@ class GameSocket {
     this.onDisconnect = null;
   }
 
-  connect(wsPort, gameId, playerId, color) {
+  connect(gameId) {
     return new Promise((resolve, reject) => {
-      const url = `ws://${ENGINE_HOST}:${wsPort}?gameId=${gameId}&playerId=${playerId}&color=${color}`;
+      const token = localStorage.getItem('token');
+      const url = `${GATEWAY_URL}/ws/games/${gameId}?access_token=${encodeURIComponent(token)}`;
 
-      console.log('Connecting to game WebSocket:', url);
+      console.log('Connecting to game WebSocket:', gameId);
       this.socket = new WebSocket(url);
 
       this.socket.onopen = () => {
@@ -50,18 +54,18 @@ class GameSocket {
 
       this.socket.onclose = (event) => {
         console.log('WebSocket closed:', event.code, event.reason);
-        this.handleDisconnect(wsPort, gameId, playerId, color);
+        this.handleDisconnect(gameId);
       };
     });
   }
 
-  handleDisconnect(wsPort, gameId, playerId, color) {
+  handleDisconnect(gameId) {
     if (this.reconnectAttempts < this.maxReconnectAttempts) {
       this.reconnectAttempts++;
       console.log(`Attempting reconnection ${this.reconnectAttempts}/${this.maxReconnectAttempts}...`);
 
       setTimeout(() => {
-        this.connect(wsPort, gameId, playerId, color)
+        this.connect(gameId)
           .catch((error) => {
             console.error('Reconnection failed:', error);
             if (this.reconnectAttempts >= this.maxReconnectAttempts) {
@@ -42,7 +42,7 @@ describe('GameController', () => {
       expect(response.gameId).toBe('game-123');
       expect(response.wsPort).toBe(8080);
       expect(response.error).toBeUndefined();
-      expect(mockGameService.createGame).toHaveBeenCalledWith('game-123');
+      expect(mockGameService.createGame).toHaveBeenCalledWith('game-123', undefined, undefined);
       expect(mockWsPortGenerator).toHaveBeenCalledWith('game-123'); // Now expects gameId
     });
 
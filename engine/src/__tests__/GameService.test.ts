@@ -1,5 +1,5 @@
 import { GameService } from '../services/GameService';
-import { GameResult } from '../types';
+import { GameResult, Variant } from '../types';
 
 describe('GameService', () => {
   let gameService: GameService;
@@ -27,6 +27,22 @@ describe('GameService', () => {
       expect(() => gameService.createGame(gameId)).toThrow(`Game with ID ${gameId} already exists`);
     });
 
+    it('should start with black to move from a black-to-move FEN', () => {
+      const gameId = 'game-123';
+      const fen = 'rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1';
+      const gameState = gameService.createGame(gameId, fen);
+
+      expect(gameState.currentTurn).toBe('black');
+      expect(gameState.boardState).toBe(fen);
+    });
+
+    it('should throw error for an unsupported variant', () => {
+      expect(() => gameService.createGame('game-123', undefined, 'crazyhouse' as Variant)).toThrow(
+        'Unsupported variant: crazyhouse'
+      );
+      expect(gameService.gameExists('game-123')).toBe(false);
+    });
+
     it('should create chess instance for the game', () => {
       const gameId = 'game-123';
       gameService.createGame(gameId);
@@ -181,6 +197,52 @@ describe('GameService', () => {
       expect(result?.winner).toBe('draw');
     });
 
+    it('should detect a king reaching the center in King of the Hill', () => {
+      const gameId = 'game-123';
+      gameService.createGame(gameId, '4k3/8/8/8/8/4K3/8/8 w - - 0 1', 'kingofthehill');
+
+      const chess = gameService.getChessInstance(gameId);
+      chess?.move({ from: 'e3', to: 'e4' });
+
+      const result = gameService.checkGameOver(gameId);
+      expect(result).not.toBeNull();
+      expect(result?.reason).toBe('king_of_the_hill');
+      expect(result?.winner).toBe('white');
+    });
+
+    it('should not end a standard game when a king reaches the center', () => {
+      const gameId = 'game-123';
+      gameService.createGame(gameId, '4k3/8/8/8/8/4K3/8/7R w - - 0 1');
+
+      const chess = gameService.getChessInstance(gameId);
+      chess?.move({ from: 'e3', to: 'e4' });
+
+      expect(gameService.checkGameOver(gameId)).toBeNull();
+    });
+
+    it('should detect the third check in three-check', () => {
+      const gameId = 'game-123';
+      gameService.createGame(gameId, '4k3/8/8/8/8/8/8/R3K3 w - - 0 1', 'threecheck');
+
+      const chess = gameService.getChessInstance(gameId);
+      const moves = [
+        { from: 'a1', to: 'a8' }, // Check 1
+        { from: 'e8', to: 'e7' },
+        { from: 'a8', to: 'a7' }, // Check 2
+        { from: 'e7', to: 'e6' },
+        { from: 'a7', to: 'a6' }, // Check 3
+      ];
+      const results = moves.map((move) => {
+        chess?.move(move);
+        return gameService.checkGameOver(gameId);
+      });
+
+      expect(results.slice(0, 4)).toEqual([null, null, null, null]);
+      expect(results[4]?.reason).toBe('three_check');
+      expect(results[4]?.winner).toBe('white');
+      expect(gameService.getGame(gameId)?.checks).toEqual({ white: 3, black: 0 });
+    });
+
     it('should return null for non-existent game', () => {
       const result = gameService.checkGameOver('non-existent');
       expect(result).toBeNull();
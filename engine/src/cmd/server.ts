@@ -12,7 +12,6 @@ import { WebSocketPoolManager } from '../internal/WebSocketPoolManager';
 interface ServerConfig {
   httpPort: number;
   wsBasePort: number;
-  matchmakingURL?: string;
 }
 
 /**
@@ -46,8 +45,7 @@ export class ChessEngineServer {
     this.wsController = new WebSocketController(
       this.gameService,
       this.moveService,
-      this.connectionService,
-      config.matchmakingURL
+      this.connectionService
     );
 
     // WebSocket pool manager
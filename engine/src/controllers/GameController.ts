@@ -12,7 +12,7 @@ export class GameController {
    */
   createGame(request: CreateGameRequest): CreateGameResponse {
     try {
-      const { gameId, whitePlayerId, blackPlayerId } = request;
+      const { gameId, whitePlayerId, blackPlayerId, startFen, variant } = request;
 
       if (!gameId || !whitePlayerId || !blackPlayerId) {
         return {
@@ -34,7 +34,7 @@ export class GameController {
       }
 
       // Create the game
-      this.gameService.createGame(gameId);
+      this.gameService.createGame(gameId, startFen, variant);
 
       // Generate WebSocket port for this specific game
       const wsPort = this.wsPortGenerator(gameId);
@@ -3,21 +3,13 @@ import { GameService } from '../services/GameService';
 import { MoveService } from '../services/MoveService';
 import { ConnectionService } from '../services/ConnectionService';
 import { Player, WebSocketMessage, MoveRequest } from '../types';
-import { MatchmakingClient } from '../MatchmakingClient';
 
 export class WebSocketController {
-  private matchmakingClient: MatchmakingClient | null = null;
-
   constructor(
     private gameService: GameService,
     private moveService: MoveService,
-    private connectionService: ConnectionService,
-    matchmakingURL?: string
-  ) {
-    if (matchmakingURL) {
-      this.matchmakingClient = new MatchmakingClient(matchmakingURL);
-    }
-  }
+    private connectionService: ConnectionService
+  ) {}
 
   /**
    * Handles new WebSocket connection
@@ -121,12 +113,6 @@ export class WebSocketController {
           data: moveResponse.result,
         });
 
-        // Cleanup matchmaking for both players
-        if (this.matchmakingClient && gameState.players.white && gameState.players.black) {
-          this.matchmakingClient.cleanupPlayer(gameState.players.white.id);
-          this.matchmakingClient.cleanupPlayer(gameState.players.black.id);
-        }
-
         return { success: true };
       }
 
@@ -187,11 +173,6 @@ export class WebSocketController {
     if (color) {
       this.connectionService.removePlayer(gameId, color);
     }
-
-    // Cleanup player from matchmaking on disconnect
-    if (this.matchmakingClient) {
-      this.matchmakingClient.cleanupPlayer(playerId);
-    }
   }
 
   /**
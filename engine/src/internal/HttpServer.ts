@@ -35,9 +35,19 @@ export class HttpServer {
     });
 
     // Create a new game
+    // The server sends the ID of the request that paired the players so a
+    // match can be traced across both services' logs
     this.app.post('/game/create', (req: Request, res: Response) => {
+      const requestId = req.get('X-Request-ID');
       const response = this.gameController.createGame(req.body);
       const statusCode = response.success ? 200 : 400;
+
+      console.log(
+        `Create game ${req.body?.gameId}: ${response.success ? 'ok' : response.error} (request ${requestId ?? '-'})`
+      );
+      if (requestId) {
+        res.set('X-Request-ID', requestId);
+      }
       res.status(statusCode).json(response);
     });
 
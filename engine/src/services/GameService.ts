@@ -1,25 +1,35 @@
 import { Chess } from 'chess.js';
-import { GameState, GameResult } from '../types';
+import { GameState, GameResult, Variant } from '../types';
+
+const VARIANTS: Variant[] = ['standard', 'chess960', 'kingofthehill', 'threecheck'];
+const CENTER_SQUARES = ['d4', 'd5', 'e4', 'e5'] as const;
 
 export class GameService {
   private games: Map<string, GameState> = new Map();
   private chessInstances: Map<string, Chess> = new Map();
 
   /**
-   * Creates a new game with the given ID
+   * Creates a new game with the given ID, optionally from a starting FEN and
+   * in a variant. Chess960 positions are played without castling.
    */
-  createGame(gameId: string): GameState {
+  createGame(gameId: string, startFen?: string, variant: Variant = 'standard'): GameState {
     if (this.games.has(gameId)) {
       throw new Error(`Game with ID ${gameId} already exists`);
     }
+    if (!VARIANTS.includes(variant)) {
+      throw new Error(`Unsupported variant: ${variant}`);
+    }
 
-    const chess = new Chess();
+    // Throws if the FEN is invalid
+    const chess = startFen ? new Chess(startFen) : new Chess();
     const gameState: GameState = {
       gameId,
+      variant,
       players: {},
       boardState: chess.fen(),
-      currentTurn: 'white',
+      currentTurn: chess.turn() === 'w' ? 'white' : 'black',
       isGameOver: false,
+      checks: { white: 0, black: 0 },
     };
 
     this.games.set(gameId, gameState);
@@ -77,14 +87,21 @@ export class GameService {
   }
 
   /**
-   * Checks if the game is over and returns the result
+   * Checks if the game is over and returns the result. Must be called once
+   * after every move, as it also counts checks for three-check.
    */
   checkGameOver(gameId: string): GameResult | null {
     const chess = this.chessInstances.get(gameId);
-    if (!chess) {
+    const gameState = this.games.get(gameId);
+    if (!chess || !gameState) {
       return null;
     }
 
+    const variantResult = this.checkVariantGameOver(gameState, chess);
+    if (variantResult) {
+      return variantResult;
+    }
+
     if (chess.isCheckmate()) {
       const winner = chess.turn() === 'w' ? 'black' : 'white';
       return {
@@ -113,6 +130,35 @@ export class GameService {
     return null;
   }
 
+  /**
+   * Applies the win conditions of King of the Hill and three-check for the
+   * side that just moved
+   */
+  private checkVariantGameOver(gameState: GameState, chess: Chess): GameResult | null {
+    const mover = chess.turn() === 'w' ? 'black' : 'white';
+    const moverColor = mover === 'white' ? 'w' : 'b';
+
+    if (gameState.variant === 'kingofthehill') {
+      const onCenter = CENTER_SQUARES.some((square) => {
+        const piece = chess.get(square);
+        return piece && piece.type === 'k' && piece.color === moverColor;
+      });
+      if (onCenter) {
+        return { winner: mover, reason: 'king_of_the_hill', timestamp: Date.now() };
+      }
+    }
+
+    if (gameState.variant === 'threecheck' && chess.inCheck()) {
+      const checks = (gameState.checks ??= { white: 0, black: 0 });
+      checks[mover] += 1;
+      if (checks[mover] >= 3) {
+        return { winner: mover, reason: 'three_check', timestamp: Date.now() };
+      }
+    }
+
+    return null;
+  }
+
   /**
    * Removes a game
    */
@@ -6,8 +6,11 @@ export interface Player {
   color: 'white' | 'black';
 }
 
+export type Variant = 'standard' | 'chess960' | 'kingofthehill' | 'threecheck';
+
 export interface GameState {
   gameId: string;
+  variant?: Variant; // Defaults to standard chess
   players: {
     white?: Player;
     black?: Player;
@@ -16,11 +19,12 @@ export interface GameState {
   currentTurn: 'white' | 'black';
   isGameOver: boolean;
   result?: GameResult;
+  checks?: { white: number; black: number }; // Checks given, for three-check
 }
 
 export interface GameResult {
   winner?: 'white' | 'black' | 'draw';
-  reason: 'checkmate' | 'stalemate' | 'draw' | 'resignation';
+  reason: 'checkmate' | 'stalemate' | 'draw' | 'resignation' | 'king_of_the_hill' | 'three_check';
   timestamp: number;
 }
 
@@ -53,6 +57,8 @@ export interface CreateGameRequest {
   gameId: string;
   whitePlayerId: string;
   blackPlayerId: string;
+  startFen?: string; // Starting position; defaults to the standard initial position
+  variant?: Variant; // Defaults to standard chess
 }
 
 export interface CreateGameResponse {
@@ -1,32 +1,130 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/ajlaz/checkmAIt/server/config"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
+// SessionChecker reports whether the login session an access token was
+// issued under has been revoked
+type SessionChecker interface {
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// APIKeyAuthenticator resolves a personal API key to the key's record,
+// rejecting unknown and revoked keys
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error)
+}
+
 type API struct {
 	*gin.Engine
-	middlewares []gin.HandlerFunc
-	jwtSecret   string
+	middlewares    []gin.HandlerFunc
+	jwtSecret      string
+	accessTokenTTL time.Duration
+	corsOrigins    []string
+	sessions       SessionChecker
+	apiKeys        APIKeyAuthenticator
+	rateLimits     map[string]ratelimit.ServiceInterface // By route group; absent groups are unlimited
 }
 
-func New(cfg *config.Config) *API {
+// Route groups with their own request rate limits
+const (
+	RateLimitAuth        = "auth"
+	RateLimitModels      = "models"
+	RateLimitMatchmaking = "matchmaking"
+	RateLimitDefault     = "default"
+)
+
+func New(cfg *config.Config, sessions SessionChecker, apiKeys APIKeyAuthenticator) (*API, error) {
 	api := &API{
-		Engine:      gin.Default(),
-		middlewares: []gin.HandlerFunc{},
-		jwtSecret:   cfg.Auth.JWTSecret,
+		Engine:         gin.New(),
+		middlewares:    []gin.HandlerFunc{},
+		jwtSecret:      cfg.Auth.JWTSecret,
+		accessTokenTTL: cfg.Auth.AccessTokenTTL,
+		corsOrigins:    cfg.HTTP.CORSOrigins,
+		sessions:       sessions,
+		apiKeys:        apiKeys,
+		rateLimits:     newRateLimits(cfg.RateLimit),
+	}
+
+	// Client IPs key the login limiters and rate limits, so X-Forwarded-For
+	// is only believed from configured proxies
+	if err := api.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
 	}
 
+	// Access logs are written by RequestLogger rather than gin's logger.
+	// Tracing comes first so the logger can pick up the trace ID.
+	api.Use(gin.Recovery(), TracingMiddleware(cfg.Tracing.ServiceName), RequestLogger(), MetricsMiddleware())
+	api.Use(BodyLimitMiddleware(int64(cfg.HTTP.MaxBodyBytes)))
+
 	// Add CORS middleware with environment-based configuration
 	api.Use(CORSMiddleware(cfg.HTTP.CORSOrigins, cfg.HTTP.CORSMethods, cfg.HTTP.CORSHeaders))
 
 	api.RegisterDefaultRoutes()
 
-	return api
+	return api, nil
 }
 
 // GetJWTSecret returns the JWT secret from the API
 func (a *API) GetJWTSecret() string {
 	return a.jwtSecret
 }
+
+// GetAccessTokenTTL returns how long issued access tokens are valid
+func (a *API) GetAccessTokenTTL() time.Duration {
+	return a.accessTokenTTL
+}
+
+// Sessions returns the checker used to reject tokens of revoked sessions
+func (a *API) Sessions() SessionChecker {
+	return a.sessions
+}
+
+// APIKeys returns the authenticator for personal API keys
+func (a *API) APIKeys() APIKeyAuthenticator {
+	return a.apiKeys
+}
+
+// RateLimit returns middleware applying group's request rate limit. Place
+// it after the auth middleware so requests are counted per user rather than
+// per client IP.
+func (a *API) RateLimit(group string) gin.HandlerFunc {
+	limiter, ok := a.rateLimits[group]
+	if !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return RateLimitMiddleware(group, limiter)
+}
+
+// OriginAllowed reports whether origin is one of the configured CORS origins
+func (a *API) OriginAllowed(origin string) bool {
+	return originAllowed(a.corsOrigins, origin)
+}
+
+// newRateLimits creates a limiter for every route group whose limit is
+// enabled
+func newRateLimits(cfg config.RateLimitConfig) map[string]ratelimit.ServiceInterface {
+	limits := make(map[string]ratelimit.ServiceInterface)
+	for group, policy := range map[string]config.RatePolicy{
+		RateLimitAuth:        cfg.Auth,
+		RateLimitModels:      cfg.Models,
+		RateLimitMatchmaking: cfg.Matchmaking,
+		RateLimitDefault:     cfg.Default,
+	} {
+		if policy.PerMinute > 0 {
+			limits[group] = ratelimit.NewService(ratelimit.Policy{
+				PerMinute: policy.PerMinute,
+				Burst:     policy.Burst,
+			})
+		}
+	}
+	return limits
+}
@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/limiter"
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
+	"github.com/ajlaz/checkmAIt/server/services/user"
+	"github.com/ajlaz/checkmAIt/server/services/user_model"
+)
+
+type Handler struct {
+	*api.API
+
+	userService        user.ServiceInterface
+	modelService       user_model.ServiceInterface
+	matchmakingService matchmaking.ServiceInterface
+	limiter            limiter.ServiceInterface
+	jwtSecret          string
+}
+
+// NewHandler registers the admin routes. Lockouts are listed from lim's
+// store, which every login limiter shares.
+func NewHandler(a *api.API, userService user.ServiceInterface, modelService user_model.ServiceInterface, matchmakingService matchmaking.ServiceInterface, lim limiter.ServiceInterface) *Handler {
+	h := &Handler{
+		API:                a,
+		userService:        userService,
+		modelService:       modelService,
+		matchmakingService: matchmakingService,
+		limiter:            lim,
+		jwtSecret:          a.GetJWTSecret(),
+	}
+
+	h.registerRoutes()
+
+	return h
+}
+
+func (h *Handler) registerRoutes() {
+	// Moderation routes - require a login session with the moderator role
+	// or higher; API keys are never accepted
+	adminGroup := h.Group("/admin")
+	adminGroup.Use(api.JWTAuthMiddleware(h.jwtSecret, h.Sessions(), nil), api.RequireRole(model.RoleModerator), h.RateLimit(api.RateLimitDefault))
+	{
+		adminGroup.GET("/users", h.ListUsers)
+		adminGroup.POST("/users/:id/ban", h.BanUser)
+		adminGroup.DELETE("/users/:id/ban", h.UnbanUser)
+		adminGroup.DELETE("/models/:id", h.DeleteModel)
+		adminGroup.GET("/matches", h.ListMatches)
+		adminGroup.DELETE("/matches/:matchId", h.CancelMatch)
+		adminGroup.GET("/lockouts", h.ListLockouts)
+
+		// Changing roles and ratings is reserved for admins
+		adminOnly := api.RequireRole(model.RoleAdmin)
+		adminGroup.PUT("/users/:id/role", adminOnly, h.SetUserRole)
+		adminGroup.POST("/models/:id/rating/reset", adminOnly, h.ResetModelRating)
+	}
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLockoutLimit is how many lockouts are listed when no limit is given
+const defaultLockoutLimit = 100
+
+// ListLockouts lists the most recent login lockouts, newest first
+func (h *Handler) ListLockouts(c *gin.Context) {
+	limit := defaultLockoutLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	lockouts, err := h.limiter.Lockouts(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lockouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"count":    len(lockouts),
+		"lockouts": lockouts,
+	})
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
+	"github.com/gin-gonic/gin"
+)
+
+type CancelMatchRequest struct {
+	Reason string `json:"reason"` // Shown to both players; defaults to "cancelled by a moderator"
+}
+
+// ListMatches lists every live match
+func (h *Handler) ListMatches(c *gin.Context) {
+	matches, err := h.matchmakingService.ListMatches(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(matches),
+		"matches": matches,
+	})
+}
+
+// CancelMatch ends a live match without a result
+func (h *Handler) CancelMatch(c *gin.Context) {
+	var req CancelMatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "cancelled by a moderator"
+	}
+
+	err := h.matchmakingService.CancelMatch(c.Request.Context(), c.Param("matchId"), req.Reason)
+	if errors.Is(err, matchmaking.ErrMatchNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
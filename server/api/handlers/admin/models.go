@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteModel deletes a model. Games it played are kept without it.
+func (h *Handler) DeleteModel(c *gin.Context) {
+	modelID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid model ID"})
+		return
+	}
+
+	if err := h.modelService.DeleteModel(c.Request.Context(), modelID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to delete model: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ResetModelRating puts a model back at the default rating in every variant
+func (h *Handler) ResetModelRating(c *gin.Context) {
+	modelID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid model ID"})
+		return
+	}
+
+	if err := h.modelService.ResetRating(c.Request.Context(), modelID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to reset rating: " + err.Error()})
+		return
+	}
+
+	model, err := h.modelService.GetModelByID(c.Request.Context(), modelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve model"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"model":   model,
+	})
+}
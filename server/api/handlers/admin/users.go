@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/gin-gonic/gin"
+)
+
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ListUsers lists every user with their role and ban status
+func (h *Handler) ListUsers(c *gin.Context) {
+	users, err := h.userService.ListUsers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(users),
+		"users":   users,
+	})
+}
+
+// BanUser bans a user, logging them out everywhere. Moderators can only ban
+// users with a lower role than their own.
+func (h *Handler) BanUser(c *gin.Context) {
+	target, ok := h.moderatableUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.BanUser(c.Request.Context(), int(target.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// UnbanUser lifts a user's ban
+func (h *Handler) UnbanUser(c *gin.Context) {
+	target, ok := h.moderatableUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.UnbanUser(c.Request.Context(), int(target.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unban user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// SetUserRole changes a user's role. The user has to log in again for the
+// new role to take effect.
+func (h *Handler) SetUserRole(c *gin.Context) {
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if !model.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role: " + req.Role})
+		return
+	}
+
+	target, ok := h.moderatableUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.SetUserRole(c.Request.Context(), int(target.ID), req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set user role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// moderatableUser loads the user named by the id parameter and checks that
+// the caller outranks them, responding with an error if not
+func (h *Handler) moderatableUser(c *gin.Context) (*model.User, bool) {
+	id := c.Param("id")
+	if _, err := strconv.Atoi(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	target, err := h.userService.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+
+	callerID, _ := api.UserIDFromContext(c)
+	if int(target.ID) == callerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot moderate your own account"})
+		return nil, false
+	}
+
+	if model.RoleAtLeast(target.Role, api.RoleFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot moderate a user with an equal or higher role"})
+		return nil, false
+	}
+
+	return target, true
+}
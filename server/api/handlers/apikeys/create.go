@@ -0,0 +1,52 @@
+package apikeys
+
+import (
+	"net/http"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+}
+
+type CreateAPIKeyResponse struct {
+	Key    string        `json:"key"` // Only ever shown here
+	APIKey *model.APIKey `json:"api_key"`
+}
+
+// CreateAPIKey creates a personal API key for the authenticated user
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !model.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+	}
+
+	apiKey, key, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), userID, req.Name, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create API key: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{
+		Key:    key,
+		APIKey: apiKey,
+	})
+}
@@ -0,0 +1,37 @@
+package apikeys
+
+import (
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/services/api_key"
+)
+
+type Handler struct {
+	*api.API
+
+	apiKeyService api_key.ServiceInterface
+	jwtSecret     string
+}
+
+func NewHandler(a *api.API, apiKeyService api_key.ServiceInterface) *Handler {
+	h := &Handler{
+		API:           a,
+		apiKeyService: apiKeyService,
+		jwtSecret:     a.GetJWTSecret(),
+	}
+
+	h.registerRoutes()
+
+	return h
+}
+
+func (h *Handler) registerRoutes() {
+	// API keys are managed with a login session only, so a leaked key
+	// cannot be used to mint more keys
+	keyGroup := h.Group("/api-keys")
+	keyGroup.Use(api.JWTAuthMiddleware(h.jwtSecret, h.Sessions(), nil), h.RateLimit(api.RateLimitDefault))
+	{
+		keyGroup.POST("", h.CreateAPIKey)
+		keyGroup.GET("", h.ListAPIKeys)
+		keyGroup.DELETE("/:id", h.RevokeAPIKey)
+	}
+}
@@ -0,0 +1,30 @@
+package apikeys
+
+import (
+	"net/http"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/gin-gonic/gin"
+)
+
+// ListAPIKeys lists the authenticated user's API keys. Key values are never
+// returned, only their prefixes.
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"count":    len(keys),
+		"api_keys": keys,
+	})
+}
@@ -0,0 +1,37 @@
+package apikeys
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/apikeys"
+	"github.com/gin-gonic/gin"
+)
+
+// RevokeAPIKey revokes one of the authenticated user's API keys
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), userID, keyID); err != nil {
+		if errors.Is(err, apikeys.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
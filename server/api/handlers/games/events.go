@@ -0,0 +1,89 @@
+package games
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
+)
+
+// engineMessage is the envelope of every frame the engine sends to players
+type engineMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// engineMoveData is the part of a move response the server tracks
+type engineMoveData struct {
+	Success    bool                    `json:"success"`
+	BoardState string                  `json:"boardState"` // FEN after the move
+	GameOver   bool                    `json:"gameOver"`
+	Result     *matchmaking.GameResult `json:"result"`
+}
+
+// observeEngineFrame turns engine frames seen by the gateway into match
+// lifecycle events
+func (h *Handler) observeEngineFrame(ctx context.Context, gameID string, userID int, frame []byte) {
+	var msg engineMessage
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return
+	}
+
+	switch msg.Type {
+	case "connection":
+		h.reportGameEvent(ctx, gameID, matchmaking.GameEvent{
+			Type:   matchmaking.GameEventPlayerConnected,
+			UserID: userID,
+		})
+	case "game_over":
+		var result matchmaking.GameResult
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			return
+		}
+		h.reportGameEvent(ctx, gameID, matchmaking.GameEvent{
+			Type:   matchmaking.GameEventGameOver,
+			Result: &result,
+		})
+	case "move":
+		// Move responses only go to the player who moved, so they time
+		// that player's clock
+		var move engineMoveData
+		if err := json.Unmarshal(msg.Data, &move); err != nil || !move.Success {
+			return
+		}
+		event := matchmaking.GameEvent{
+			Type:   matchmaking.GameEventMove,
+			UserID: userID,
+		}
+		// The engine's own result takes precedence over adjudication
+		if !move.GameOver {
+			event.FEN = move.BoardState
+		}
+		h.reportGameEvent(ctx, gameID, event)
+		if move.GameOver && move.Result != nil {
+			h.reportGameEvent(ctx, gameID, matchmaking.GameEvent{
+				Type:   matchmaking.GameEventGameOver,
+				Result: move.Result,
+			})
+		}
+	}
+}
+
+// reportGameEvent forwards an event to matchmaking. Both players' gateways
+// report the end of the game, so events for a match that has already been
+// released are expected and ignored. The event is applied even if the
+// player's connection has gone, since it may carry the game's result.
+func (h *Handler) reportGameEvent(ctx context.Context, gameID string, event matchmaking.GameEvent) {
+	logger := logging.FromContext(ctx)
+
+	err := h.svc.MatchmakingService.HandleGameEvent(context.WithoutCancel(ctx), gameID, event)
+	switch {
+	case err == nil:
+	case errors.Is(err, matchmaking.ErrMatchNotFound):
+		logger.Debug().Str("game_id", gameID).Str("event", string(event.Type)).Msg("Ignored event for released match")
+	default:
+		logger.Error().Err(err).Str("game_id", gameID).Str("event", string(event.Type)).Msg("Failed to apply game event")
+	}
+}
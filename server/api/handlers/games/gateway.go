@@ -0,0 +1,152 @@
+package games
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// closeWriteTimeout bounds how long the gateway waits to deliver close frames
+const closeWriteTimeout = time.Second
+
+// ConnectGame upgrades the request to a WebSocket and proxies frames between
+// the player and the engine game they belong to
+func (h *Handler) ConnectGame(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Invalid user ID format"})
+		return
+	}
+
+	gameID := c.Param("gameId")
+	match, err := h.svc.MatchmakingService.GetMatchByGameID(c.Request.Context(), gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		return
+	}
+
+	// Only the two players of the match may join, and only as their own color
+	var color string
+	switch userID {
+	case match.WhitePlayer:
+		color = "white"
+	case match.BlackPlayer:
+		color = "black"
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a player in this game"})
+		return
+	}
+
+	engineURL, err := h.svc.EngineService.GameSocketURL(match.Engine, match.WSPort, gameID, strconv.Itoa(userID), color)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve game server"})
+		return
+	}
+
+	// Dial the engine before upgrading so failures can still be reported over HTTP
+	engineConn, _, err := websocket.DefaultDialer.DialContext(c.Request.Context(), engineURL, nil)
+	if err != nil {
+		logger.Error().Err(err).Str("game_id", gameID).Str("engine", match.Engine).Msg("Failed to connect to engine game socket")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Game server unavailable"})
+		return
+	}
+
+	clientConn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		engineConn.Close()
+		return
+	}
+
+	logger.Debug().Str("game_id", gameID).Int("user_id", userID).Str("color", color).Msg("Proxying game socket")
+
+	ctx := c.Request.Context()
+	proxy(clientConn, engineConn, func(frame []byte) {
+		h.observeEngineFrame(ctx, gameID, userID, frame)
+	})
+
+	// Start the reconnect grace period; this is a no-op once the game is over
+	h.reportGameEvent(ctx, gameID, matchmaking.GameEvent{
+		Type:   matchmaking.GameEventPlayerDisconnected,
+		UserID: userID,
+	})
+}
+
+// checkOrigin accepts non-browser clients and browser origins allowed by CORS
+func (h *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || h.api.OriginAllowed(origin)
+}
+
+// proxy copies frames in both directions until either side closes, then
+// closes the other side with the same close code. Every frame sent by the
+// engine is passed to observe before being forwarded.
+func proxy(clientConn, engineConn *websocket.Conn, observe func(frame []byte)) {
+	defer clientConn.Close()
+	defer engineConn.Close()
+
+	clientErr := make(chan error, 1)
+	engineErr := make(chan error, 1)
+	go copyFrames(engineConn, clientConn, clientErr, nil)
+	go copyFrames(clientConn, engineConn, engineErr, observe)
+
+	select {
+	case err := <-clientErr:
+		writeClose(engineConn, err)
+	case err := <-engineErr:
+		writeClose(clientConn, err)
+	}
+}
+
+// copyFrames forwards every message read from src to dst, reporting the
+// first read or write error. observe, if non-nil, sees each text frame.
+func copyFrames(dst, src *websocket.Conn, errc chan<- error, observe func(frame []byte)) {
+	for {
+		messageType, data, err := src.ReadMessage()
+		if err != nil {
+			errc <- err
+			return
+		}
+		if observe != nil && messageType == websocket.TextMessage {
+			observe(data)
+		}
+		if err := dst.WriteMessage(messageType, data); err != nil {
+			errc <- err
+			return
+		}
+	}
+}
+
+// writeClose sends a close frame mirroring the error that ended the other side
+func writeClose(conn *websocket.Conn, err error) {
+	code := websocket.CloseNormalClosure
+	text := ""
+
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case websocket.CloseNoStatusReceived, websocket.CloseAbnormalClosure:
+			// Reserved codes that must not be sent on the wire
+		default:
+			code = closeErr.Code
+			text = closeErr.Text
+		}
+	} else {
+		code = websocket.CloseGoingAway
+	}
+
+	_ = conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, text),
+		time.Now().Add(closeWriteTimeout),
+	)
+}
@@ -0,0 +1,40 @@
+package games
+
+import (
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services"
+	"github.com/gorilla/websocket"
+)
+
+type Handler struct {
+	api      *api.API
+	svc      services.Services
+	upgrader websocket.Upgrader
+}
+
+func NewHandler(a *api.API, svc services.Services) *Handler {
+	h := &Handler{
+		api: a,
+		svc: svc,
+	}
+
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
+
+	h.registerRoutes()
+	return h
+}
+
+func (h *Handler) registerRoutes() {
+	// Game sockets authenticate with the access_token query parameter since
+	// browsers cannot set headers on WebSocket connections
+	wsGroup := h.api.Group("/ws/games")
+	wsGroup.Use(api.JWTQueryAuthMiddleware(h.api.GetJWTSecret(), h.api.Sessions(), h.api.APIKeys()), api.RequireScope(model.ScopeMatchesRun), h.api.RateLimit(api.RateLimitDefault))
+	{
+		wsGroup.GET("/:gameId", h.ConnectGame)
+	}
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/services/health"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	*api.API
+
+	healthService health.ServiceInterface
+}
+
+func NewHandler(a *api.API, healthService health.ServiceInterface) *Handler {
+	h := &Handler{
+		API:           a,
+		healthService: healthService,
+	}
+
+	h.registerRoutes()
+
+	return h
+}
+
+func (h *Handler) registerRoutes() {
+	h.GET("/livez", h.Live)
+	h.GET("/readyz", h.Ready)
+}
+
+// Live reports that the process is up and serving requests. It checks no
+// dependencies, so an outage elsewhere doesn't get the server restarted.
+func (h *Handler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Ready runs the readiness checks and answers 503 if any of them fails, so
+// traffic is only routed to a server that can handle it
+func (h *Handler) Ready(c *gin.Context) {
+	report := h.healthService.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
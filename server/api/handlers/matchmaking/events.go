@@ -0,0 +1,137 @@
+package matchmaking
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
+	"github.com/gin-gonic/gin"
+)
+
+// eventsHeartbeatInterval keeps idle event streams from being closed by proxies
+const eventsHeartbeatInterval = 15 * time.Second
+
+// StreamEvents pushes queue and match updates for the authenticated user as
+// server-sent events until the client disconnects
+func (h *Handler) StreamEvents(c *gin.Context) {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Unauthorized: Invalid user ID format",
+		})
+		return
+	}
+
+	// Subscribe before reading the current status so no update is missed
+	events, unsubscribe := h.svc.MatchmakingService.Subscribe(userID)
+	defer unsubscribe()
+
+	match, queuePosition, err := h.svc.MatchmakingService.GetPlayerStatus(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get queue status: " + err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Send the current state so clients don't need a separate status call
+	switch {
+	case match != nil:
+		c.SSEvent(string(matchmaking.EventMatchFound), matchStatusResponse(match, userID))
+	case queuePosition >= 0:
+		c.SSEvent(string(matchmaking.EventQueuePosition), QueueStatusResponse{
+			Status:        "queued",
+			QueuePosition: queuePosition,
+		})
+	default:
+		c.SSEvent("not_queued", gin.H{"status": "not_queued"})
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"time": time.Now().Unix()})
+			return true
+		case event, open := <-events:
+			if !open {
+				return false
+			}
+			c.SSEvent(string(event.Type), eventResponse(event, userID))
+			return true
+		}
+	})
+}
+
+// eventResponse renders a service event from the point of view of userID
+func eventResponse(event matchmaking.Event, userID int) QueueStatusResponse {
+	switch event.Type {
+	case matchmaking.EventMatchFound:
+		return matchStatusResponse(event.Match, userID)
+	case matchmaking.EventMatchCompleted:
+		resp := matchStatusResponse(event.Match, userID)
+		resp.Status = "completed"
+		resp.Reason = event.Reason
+		return resp
+	case matchmaking.EventMatchCancelled:
+		resp := matchStatusResponse(event.Match, userID)
+		resp.Status = "cancelled"
+		resp.Reason = event.Reason
+		return resp
+	case matchmaking.EventQueueClosed:
+		return QueueStatusResponse{
+			Status: "not_queued",
+			Reason: event.Reason,
+		}
+	default:
+		return QueueStatusResponse{
+			Status:        "queued",
+			QueuePosition: event.QueuePosition,
+		}
+	}
+}
+
+// matchStatusResponse describes a match from the point of view of userID
+func matchStatusResponse(match *matchmaking.Match, userID int) QueueStatusResponse {
+	playerColor := "white"
+	if match.BlackPlayer == userID {
+		playerColor = "black"
+	}
+
+	// The opponent is whichever player is not the requesting user
+	opponentModelID := match.Player1.ModelID
+	if match.Player1.UserID == userID {
+		opponentModelID = match.Player2.ModelID
+	}
+
+	// A reconnecting player gets their game and color back along with the
+	// deadline for rejoining it
+	var reconnectDeadline *time.Time
+	if match.Player1.UserID == userID {
+		reconnectDeadline = match.Player1.ReconnectDeadline
+	} else {
+		reconnectDeadline = match.Player2.ReconnectDeadline
+	}
+
+	return QueueStatusResponse{
+		Status:            "matched",
+		GameID:            match.GameID,
+		WSPort:            match.WSPort,
+		MatchID:           match.ID,
+		PlayerColor:       playerColor,
+		OpponentModelID:   opponentModelID,
+		MatchStatus:       match.Status,
+		ReconnectDeadline: reconnectDeadline,
+	}
+}
@@ -2,6 +2,7 @@ package matchmaking
 
 import (
 	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
 	"github.com/ajlaz/checkmAIt/server/services"
 )
 
@@ -24,18 +25,20 @@ func NewHandler(api *api.API, svc services.Services) *Handler {
 func (h *Handler) registerRoutes() {
 	matchmakingGroup := h.api.Group("/matchmaking")
 
-	// User-facing routes (require JWT auth)
+	// User-facing routes (require JWT or an API key with the matches:run scope)
 	authRoutes := matchmakingGroup.Group("")
-	authRoutes.Use(api.JWTAuthMiddleware(h.api.GetJWTSecret()))
+	authRoutes.Use(api.JWTAuthMiddleware(h.api.GetJWTSecret(), h.api.Sessions(), h.api.APIKeys()), api.RequireScope(model.ScopeMatchesRun), h.api.RateLimit(api.RateLimitMatchmaking))
 	{
 		authRoutes.POST("/join", h.JoinQueue)
 		authRoutes.POST("/leave", h.LeaveQueue)
 		authRoutes.GET("/status", h.GetQueueStatus)
+		authRoutes.GET("/openings", h.ListOpeningSuites)
 	}
 
-	// Internal service routes (no auth required - for engine communication)
+	// Event stream (token may be passed as a query parameter for EventSource)
+	streamRoutes := matchmakingGroup.Group("")
+	streamRoutes.Use(api.JWTQueryAuthMiddleware(h.api.GetJWTSecret(), h.api.Sessions(), h.api.APIKeys()), api.RequireScope(model.ScopeMatchesRun), h.api.RateLimit(api.RateLimitMatchmaking))
 	{
-		matchmakingGroup.POST("/cleanup/match/:matchId", h.CleanupMatch)
-		matchmakingGroup.POST("/cleanup/player/:userId", h.CleanupPlayer)
+		streamRoutes.GET("/events", h.StreamEvents)
 	}
 }
@@ -1,70 +1,108 @@
 package matchmaking
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownRetryAfter is the Retry-After, in seconds, sent with joins refused
+// while the server shuts down
+const shutdownRetryAfter = "5"
+
 type JoinQueueRequest struct {
 	ModelID int `json:"modelId" binding:"required"`
+	matchmaking.GameSettings
 }
 
 type QueueStatusResponse struct {
-	Status          string `json:"status"` // "queued", "matched"
+	Status          string `json:"status"` // "queued", "matched" or "not_queued"
 	QueuePosition   int    `json:"queuePosition,omitempty"`
 	GameID          string `json:"gameId,omitempty"`
 	WSPort          int    `json:"wsPort,omitempty"`
 	MatchID         string `json:"matchId,omitempty"`
 	PlayerColor     string `json:"playerColor,omitempty"`     // "white" or "black"
 	OpponentModelID int    `json:"opponentModelId,omitempty"` // Added for rating updates
+	Reason          string `json:"reason,omitempty"`          // Set when a match is cancelled or the queue closes
+	MatchStatus     string `json:"matchStatus,omitempty"`     // Lifecycle status of the match
+
+	// Set while the player is disconnected and may still rejoin the game
+	ReconnectDeadline *time.Time `json:"reconnectDeadline,omitempty"`
 }
 
 func (h *Handler) JoinQueue(c *gin.Context) {
 	// Extract user ID from context (set by JWT middleware)
-	userIDVal, exists := c.Get("userID")
-	if !exists {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Unauthorized: No user ID found",
 		})
 		return
 	}
 
-	// Convert userID to int based on the type
-	var userID int
+	// Parse request body
+	var req JoinQueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data: " + err.Error(),
+		})
+		return
+	}
 
-	// JWT claims are stored as float64 when unmarshalled
-	switch v := userIDVal.(type) {
-	case float64:
-		userID = int(v)
-	case string:
-		var err error
-		userID, err = strconv.Atoi(v)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Unauthorized: Invalid user ID format",
-			})
-			return
-		}
-	default:
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized: Invalid user ID format",
+	// Players may only queue their own models; other users' models are
+	// reported as missing
+	userModel, err := h.svc.ModelService.GetModelByID(c.Request.Context(), req.ModelID)
+	if err != nil || userModel.UserID != userID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to join queue: model not found",
 		})
 		return
 	}
 
-	// Parse request body
-	var req JoinQueueRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	// Only queue models that can play the requested variant, so incompatible
+	// bots are never paired
+	variant := req.Variant
+	if variant == "" {
+		variant = model.VariantStandard
+	}
+	if !userModel.SupportsVariant(variant) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data: " + err.Error(),
+			"error": "Failed to join queue: model does not support " + variant,
+		})
+		return
+	}
+
+	wait, err := h.svc.GameService.DailyLimitWait(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to join queue: could not check daily game limit",
+		})
+		return
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Daily game limit reached, try again later",
 		})
 		return
 	}
 
 	// Add user to matchmaking queue
-	match, err := h.svc.MatchmakingService.AddToQueue(userID, req.ModelID)
+	match, err := h.svc.MatchmakingService.AddToQueue(c.Request.Context(), userID, req.ModelID, req.GameSettings)
+	if errors.Is(err, matchmaking.ErrShuttingDown) {
+		c.Header("Retry-After", shutdownRetryAfter)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Matchmaking is restarting, try again shortly",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Failed to join queue: " + err.Error(),
@@ -82,64 +120,21 @@ func (h *Handler) JoinQueue(c *gin.Context) {
 	}
 
 	// User was matched immediately
-	// Determine player color and opponent model ID
-	playerColor := "white"
-	var opponentModelID int
-
-	if match.BlackPlayer == userID {
-		playerColor = "black"
-		// If we're black, the opponent is white (player1)
-		opponentModelID = match.Player1.ModelID
-	} else {
-		// If we're white, the opponent is black (player2)
-		opponentModelID = match.Player2.ModelID
-	}
-
-	c.JSON(http.StatusOK, QueueStatusResponse{
-		Status:          "matched",
-		GameID:          match.GameID,
-		WSPort:          match.WSPort,
-		MatchID:         match.ID,
-		PlayerColor:     playerColor,
-		OpponentModelID: opponentModelID,
-	})
+	c.JSON(http.StatusOK, matchStatusResponse(match, userID))
 }
 
 func (h *Handler) LeaveQueue(c *gin.Context) {
 	// Extract user ID from context (set by JWT middleware)
-	userIDVal, exists := c.Get("userID")
-	if !exists {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Unauthorized: No user ID found",
 		})
 		return
 	}
 
-	// Convert userID to int based on the type
-	var userID int
-
-	// JWT claims are stored as float64 when unmarshalled
-	switch v := userIDVal.(type) {
-	case float64:
-		userID = int(v)
-	case string:
-		var err error
-		userID, err = strconv.Atoi(v)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Unauthorized: Invalid user ID format",
-			})
-			return
-		}
-	default:
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized: Invalid user ID format",
-		})
-		return
-	}
-
 	// Remove user from queue
-	err := h.svc.MatchmakingService.RemoveFromQueue(userID)
+	err := h.svc.MatchmakingService.RemoveFromQueue(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Failed to leave queue: " + err.Error(),
@@ -155,39 +150,16 @@ func (h *Handler) LeaveQueue(c *gin.Context) {
 
 func (h *Handler) GetQueueStatus(c *gin.Context) {
 	// Extract user ID from context (set by JWT middleware)
-	userIDVal, exists := c.Get("userID")
-	if !exists {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Unauthorized: No user ID found",
 		})
 		return
 	}
 
-	// Convert userID to int based on the type
-	var userID int
-
-	// JWT claims are stored as float64 when unmarshalled
-	switch v := userIDVal.(type) {
-	case float64:
-		userID = int(v)
-	case string:
-		var err error
-		userID, err = strconv.Atoi(v)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Unauthorized: Invalid user ID format",
-			})
-			return
-		}
-	default:
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized: Invalid user ID format",
-		})
-		return
-	}
-
 	// Get player status
-	match, queuePosition, err := h.svc.MatchmakingService.GetPlayerStatus(userID)
+	match, queuePosition, err := h.svc.MatchmakingService.GetPlayerStatus(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get queue status: " + err.Error(),
@@ -197,27 +169,7 @@ func (h *Handler) GetQueueStatus(c *gin.Context) {
 
 	// If match exists, return match details
 	if match != nil {
-		// Determine player color and opponent model ID
-		playerColor := "white"
-		var opponentModelID int
-
-		if match.BlackPlayer == userID {
-			playerColor = "black"
-			// If we're black, the opponent is white (player1)
-			opponentModelID = match.Player1.ModelID
-		} else {
-			// If we're white, the opponent is black (player2)
-			opponentModelID = match.Player2.ModelID
-		}
-
-		c.JSON(http.StatusOK, QueueStatusResponse{
-			Status:          "matched",
-			GameID:          match.GameID,
-			WSPort:          match.WSPort,
-			MatchID:         match.ID,
-			PlayerColor:     playerColor,
-			OpponentModelID: opponentModelID,
-		})
+		c.JSON(http.StatusOK, matchStatusResponse(match, userID))
 		return
 	}
 
@@ -236,57 +188,3 @@ func (h *Handler) GetQueueStatus(c *gin.Context) {
 		"message": "Player is not in the matchmaking queue",
 	})
 }
-
-func (h *Handler) CleanupMatch(c *gin.Context) {
-	matchID := c.Param("matchId")
-	if matchID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Match ID is required",
-		})
-		return
-	}
-
-	err := h.svc.MatchmakingService.RemoveMatch(matchID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Failed to cleanup match: " + err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Match cleaned up successfully",
-	})
-}
-
-func (h *Handler) CleanupPlayer(c *gin.Context) {
-	userIDStr := c.Param("userId")
-	if userIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID format",
-		})
-		return
-	}
-
-	err = h.svc.MatchmakingService.RemovePlayerFromMatch(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Failed to cleanup player: " + err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Player cleaned up successfully",
-	})
-}
@@ -0,0 +1,18 @@
+package matchmaking
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOpeningSuites returns the opening suites players can queue with
+func (h *Handler) ListOpeningSuites(c *gin.Context) {
+	suites := h.svc.OpeningService.Suites()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(suites),
+		"suites":  suites,
+	})
+}
@@ -1,9 +1,11 @@
 package models
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/services/user_model"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,42 +13,45 @@ type CreateModelRequest struct {
 	UserID int    `json:"user_id"` // Not required anymore, we get it from JWT token
 	Name   string `json:"name" binding:"required"`
 	Model  string `json:"model" binding:"required"`
+
+	Variants []string `json:"variants"` // Variants the model can play; defaults to standard
+	Public   *bool    `json:"public"`   // Whether the model is listed on the owner's profile; defaults to false
 }
 
 func (h *Handler) CreateModel(c *gin.Context) {
 	var req CreateModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		if api.BodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
 	// Extract user ID from context (set by JWT middleware)
-	userIDVal, exists := c.Get("userID")
-	if !exists {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
 		return
 	}
 
-	// Convert userID to int based on the type
-	var userID int
-
-	// JWT claims are stored as float64 when unmarshalled
-	switch v := userIDVal.(type) {
-	case float64:
-		userID = int(v)
-	case string:
-		var err error
-		userID, err = strconv.Atoi(v)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Invalid user ID format"})
-			return
-		}
-	default:
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Invalid user ID format"})
+	if err := validateVariants(req.Variants); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	model, err := h.modelService.CreateModel(userID, req.Name, req.Model)
+	public := req.Public != nil && *req.Public
+
+	model, err := h.modelService.CreateModel(c.Request.Context(), userID, req.Name, req.Model, req.Variants, public)
+	if errors.Is(err, user_model.ErrCodeTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, user_model.ErrModelLimitReached) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create model"})
 		return
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,11 +26,34 @@ func (h *Handler) GetModelByID(c *gin.Context) {
 	}
 
 	// Get model from service
-	model, err := h.modelService.GetModelByID(modelIDInt)
-	if err != nil {
+	model, err := h.modelService.GetModelByID(c.Request.Context(), modelIDInt)
+	if err != nil || !visible(c, model) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Model not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, model)
+	c.JSON(http.StatusOK, forViewer(c, model))
+}
+
+// owns reports whether the authenticated user owns m
+func owns(c *gin.Context, m *model.UserModel) bool {
+	userID, ok := api.UserIDFromContext(c)
+	return ok && userID == m.UserID
+}
+
+// visible reports whether the authenticated user may see m: their own
+// models and other users' public ones
+func visible(c *gin.Context, m *model.UserModel) bool {
+	return m.Public || owns(c, m)
+}
+
+// forViewer returns m as the authenticated user may see it. Other users'
+// public models are metadata only; their code stays with the owner.
+func forViewer(c *gin.Context, m *model.UserModel) *model.UserModel {
+	if owns(c, m) {
+		return m
+	}
+	metadata := *m
+	metadata.Model = ""
+	return &metadata
 }
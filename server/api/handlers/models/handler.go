@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/model"
 	"github.com/ajlaz/checkmAIt/server/services/user"
 	"github.com/ajlaz/checkmAIt/server/services/user_model"
 )
@@ -28,14 +29,17 @@ func NewHandler(a *api.API, userService user.ServiceInterface, modelService user
 }
 
 func (h *Handler) registerRoutes() {
-	// Models routes - require authentication
+	// Models routes - require authentication, by login or API key
 	modelGroup := h.Group("/models")
-	modelGroup.Use(api.JWTAuthMiddleware(h.jwtSecret))
+	modelGroup.Use(api.JWTAuthMiddleware(h.jwtSecret, h.Sessions(), h.APIKeys()), h.RateLimit(api.RateLimitModels))
 	{
-		modelGroup.GET("/:id", h.GetModelByID)
-		modelGroup.GET("/user/:userId", h.GetModelsByUserID)
-		modelGroup.POST("", h.CreateModel)
-		modelGroup.PUT("/:id", h.UpdateModel)
-		modelGroup.PUT("/rating", h.UpdateRating) // Rating update endpoint
+		read := api.RequireScope(model.ScopeModelsRead)
+		write := api.RequireScope(model.ScopeModelsWrite)
+
+		modelGroup.GET("/:id", read, h.GetModelByID)
+		modelGroup.GET("/:id/ratings", read, h.GetModelRatings)
+		modelGroup.GET("/user/:userId", read, h.GetModelsByUserID)
+		modelGroup.POST("", write, h.CreateModel)
+		modelGroup.PUT("/:id", write, h.UpdateModel)
 	}
 }
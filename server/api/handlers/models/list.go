@@ -25,12 +25,21 @@ func (h *Handler) GetModelsByUserID(c *gin.Context) {
 	}
 
 	// Get models from service
-	models, err := h.modelService.GetModelsByUserID(userIDInt)
+	models, err := h.modelService.GetModelsByUserID(c.Request.Context(), userIDInt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve models: " + err.Error()})
 		return
 	}
 
+	// Other users only see public models
+	visibleModels := models[:0]
+	for _, m := range models {
+		if visible(c, m) {
+			visibleModels = append(visibleModels, forViewer(c, m))
+		}
+	}
+	models = visibleModels
+
 	// Return the models (will be empty array if no models found)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1,15 +1,21 @@
 package models
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/services/user_model"
 	"github.com/gin-gonic/gin"
 )
 
 type UpdateModelRequest struct {
 	Name  string `json:"name" binding:"required"`
 	Model string `json:"model" binding:"required"` // Changed from ModelCode to Model to match frontend and CreateModelRequest
+
+	Variants []string `json:"variants"` // Omit to keep the declared variants
+	Public   *bool    `json:"public"`   // Omit to keep the model's visibility
 }
 
 // UpdateModel updates an existing model
@@ -26,8 +32,8 @@ func (h *Handler) UpdateModel(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token
-	userIDVal, exists := c.Get("userID")
-	if !exists {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"error":   "Unauthorized: No user ID found",
@@ -35,43 +41,33 @@ func (h *Handler) UpdateModel(c *gin.Context) {
 		return
 	}
 
-	// Convert userID to int based on the type
-	var userID int
-
-	// JWT claims are stored as float64 when unmarshalled
-	switch v := userIDVal.(type) {
-	case float64:
-		userID = int(v)
-	case string:
-		var err error
-		userID, err = strconv.Atoi(v)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+	// Parse request body
+	var req UpdateModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if api.BodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
 				"success": false,
-				"error":   "Internal server error: Invalid user ID format",
+				"error":   "Request body is too large",
 			})
 			return
 		}
-	default:
-		c.JSON(http.StatusUnauthorized, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "Unauthorized: Invalid user ID format",
+			"error":   "Invalid request: " + err.Error(),
 		})
 		return
 	}
 
-	// Parse request body
-	var req UpdateModelRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := validateVariants(req.Variants); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "Invalid request: " + err.Error(),
+			"error":   err.Error(),
 		})
 		return
 	}
 
 	// Check if model exists and belongs to user
-	existingModel, err := h.modelService.GetModelByID(modelID)
+	existingModel, err := h.modelService.GetModelByID(c.Request.Context(), modelID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -90,7 +86,14 @@ func (h *Handler) UpdateModel(c *gin.Context) {
 	}
 
 	// Update model
-	updatedModel, err := h.modelService.UpdateModel(modelID, req.Name, req.Model)
+	updatedModel, err := h.modelService.UpdateModel(c.Request.Context(), modelID, req.Name, req.Model, req.Variants, req.Public)
+	if errors.Is(err, user_model.ErrCodeTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/gin-gonic/gin"
+)
+
+// GetModelRatings returns a model's rating in every variant
+func (h *Handler) GetModelRatings(c *gin.Context) {
+	modelID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || modelID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid model ID"})
+		return
+	}
+
+	userModel, err := h.modelService.GetModelByID(c.Request.Context(), modelID)
+	if err != nil || !visible(c, userModel) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Model not found"})
+		return
+	}
+
+	variantRatings, err := h.modelService.GetVariantRatings(c.Request.Context(), modelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ratings: " + err.Error()})
+		return
+	}
+
+	// Standard chess ratings live on the model itself
+	ratings := map[string]int{model.VariantStandard: userModel.Rating}
+	for _, rating := range variantRatings {
+		ratings[rating.Variant] = rating.Rating
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"model_id": modelID,
+		"variants": userModel.Variants,
+		"ratings":  ratings,
+	})
+}
+
+// validateVariants rejects variants the platform doesn't support
+func validateVariants(variants []string) error {
+	for _, variant := range variants {
+		if !model.ValidVariant(variant) {
+			return fmt.Errorf("unsupported variant: %s", variant)
+		}
+	}
+	return nil
+}
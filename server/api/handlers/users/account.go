@@ -0,0 +1,146 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/services/user"
+	"github.com/gin-gonic/gin"
+)
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// VerifyEmail confirms a user's email address with the token from their
+// verification email
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	err := h.userService.VerifyEmail(c.Request.Context(), req.Token)
+	if errors.Is(err, user.ErrInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid or expired verification link",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to verify email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+	})
+}
+
+// ResendVerification sends the authenticated user a new verification email
+func (h *Handler) ResendVerification(c *gin.Context) {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Unauthorized: No user ID found",
+		})
+		return
+	}
+
+	err := h.userService.SendVerificationEmail(c.Request.Context(), userID)
+	if errors.Is(err, user.ErrEmailAlreadyVerified) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Email is already verified",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to send verification email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+	})
+}
+
+// RequestPasswordReset emails a password reset link. The response is the
+// same whether or not an account uses the address.
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to send password reset email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status": "success",
+	})
+}
+
+// ConfirmPasswordReset sets a new password with the token from a password
+// reset email. All of the user's sessions are logged out.
+func (h *Handler) ConfirmPasswordReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	// Reset tokens can't be guessed, but limit clients trying anyway
+	a := newAttempt(c, "")
+	if !h.allow(c, a) {
+		return
+	}
+	defer h.release(c, a)
+
+	err := h.userService.ResetPassword(c.Request.Context(), req.Token, req.Password)
+	if errors.Is(err, user.ErrInvalidToken) {
+		h.fail(c, a)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid or expired reset link",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset password",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+	})
+}
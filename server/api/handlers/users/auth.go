@@ -1,10 +1,16 @@
 package users
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/metrics"
 	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/user"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -20,14 +26,22 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type AuthResponse struct {
-	Token  string     `json:"token"`
-	User   model.User `json:"user"`
-	Status string     `json:"status"`
+	Token        string     `json:"token"`
+	RefreshToken string     `json:"refresh_token"`
+	ExpiresIn    int        `json:"expires_in"` // Seconds until the access token expires
+	User         model.User `json:"user"`
+	Status       string     `json:"status"`
 }
 
 // Register handles user registration
 func (h *Handler) Register(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var req RegisterRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -38,7 +52,7 @@ func (h *Handler) Register(c *gin.Context) {
 	}
 
 	// Create user in database
-	user, err := h.userService.CreateUser(req.Username, req.Email, req.Password)
+	user, err := h.userService.CreateUser(ctx, req.Username, req.Email, req.Password)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Failed to register user: " + err.Error(),
@@ -46,20 +60,14 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	// Generate token
-	token, err := h.generateJWT(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate token",
-		})
-		return
+	// Registration succeeds even if the email can't be sent; the user can
+	// ask for another one
+	if err := h.userService.SendVerificationEmail(ctx, int(user.ID)); err != nil {
+		logger := logging.FromContext(ctx)
+		logger.Warn().Err(err).Int64("user_id", user.ID).Msg("Failed to send verification email")
 	}
 
-	c.JSON(http.StatusCreated, AuthResponse{
-		Token:  token,
-		User:   *user,
-		Status: "success",
-	})
+	h.startSession(c, http.StatusCreated, user)
 }
 
 // Login handles user authentication
@@ -73,17 +81,114 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
+	// Back off repeated failures before spending time on bcrypt
+	a := newAttempt(c, req.Email)
+	if !h.allow(c, a) {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginRateLimited).Inc()
+		return
+	}
+	defer h.release(c, a)
+
 	// Authenticate user
-	user, err := h.userService.AuthenticateUser(req.Email, req.Password)
+	u, err := h.userService.AuthenticateUser(c.Request.Context(), req.Email, req.Password)
+	if errors.Is(err, user.ErrUserBanned) {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginBanned).Inc()
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Account is banned",
+		})
+		return
+	}
 	if err != nil {
+		h.fail(c, a)
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials).Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Invalid credentials",
 		})
 		return
 	}
 
-	// Generate token
-	token, err := h.generateJWT(user)
+	h.succeed(c, a)
+	metrics.Logins.Inc()
+	h.startSession(c, http.StatusOK, u)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	session, err := h.userService.RefreshSession(c.Request.Context(), req.RefreshToken)
+	if errors.Is(err, user.ErrUserBanned) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Account is banned",
+		})
+		return
+	}
+	if errors.Is(err, user.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired refresh token",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to refresh session",
+		})
+		return
+	}
+
+	h.respondWithSession(c, http.StatusOK, session)
+}
+
+// Logout revokes the session a refresh token belongs to, invalidating its
+// access and refresh tokens
+func (h *Handler) Logout(c *gin.Context) {
+	var req RefreshRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	err := h.userService.EndSession(c.Request.Context(), req.RefreshToken)
+	if err != nil && !errors.Is(err, user.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to log out",
+		})
+		return
+	}
+
+	// Logging out of an unknown or already revoked session is not an error
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+	})
+}
+
+// startSession begins a login session for user and responds with its tokens
+func (h *Handler) startSession(c *gin.Context, status int, u *model.User) {
+	session, err := h.userService.StartSession(c.Request.Context(), u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start session",
+		})
+		return
+	}
+
+	h.respondWithSession(c, status, session)
+}
+
+// respondWithSession issues an access token for the session and responds
+// with it and the session's refresh token
+func (h *Handler) respondWithSession(c *gin.Context, status int, session *user.Session) {
+	token, err := h.generateJWT(session.User, session.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to generate token",
@@ -91,21 +196,31 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, AuthResponse{
-		Token:  token,
-		User:   *user,
-		Status: "success",
+	c.JSON(status, AuthResponse{
+		Token:        token,
+		RefreshToken: session.RefreshToken,
+		ExpiresIn:    int(h.GetAccessTokenTTL().Seconds()),
+		User:         *session.User,
+		Status:       "success",
 	})
 }
 
-// Helper method to generate JWT tokens
-func (h *Handler) generateJWT(user *model.User) (string, error) {
+// Helper method to generate short-lived JWT access tokens for a session
+func (h *Handler) generateJWT(user *model.User, sessionID string) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
 	// Set custom claims
 	claims := jwt.MapClaims{
 		"user_id":  user.ID,
 		"username": user.Username,
 		"email":    user.Email,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
+		"role":     user.Role,
+		"sid":      sessionID,
+		"jti":      hex.EncodeToString(jti),
+		"exp":      time.Now().Add(h.GetAccessTokenTTL()).Unix(),
 	}
 
 	// Create token with claims
@@ -0,0 +1,103 @@
+package users
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/gin-gonic/gin"
+)
+
+// exportedModel is a model as included in a data export, with its code and
+// per-variant ratings
+type exportedModel struct {
+	*model.UserModel
+	VariantRatings []*model.VariantRating `json:"variant_ratings"`
+}
+
+// ExportMe returns a zip archive of everything stored about the
+// authenticated user: their account, models and games
+func (h *Handler) ExportMe(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
+		return
+	}
+
+	u, err := h.userService.GetUserByID(ctx, strconv.Itoa(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModels, err := h.modelService.GetModelsByUserID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve models"})
+		return
+	}
+
+	models := make([]exportedModel, 0, len(userModels))
+	for _, m := range userModels {
+		ratings, err := h.modelService.GetVariantRatings(ctx, m.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve model ratings"})
+			return
+		}
+		models = append(models, exportedModel{UserModel: m, VariantRatings: ratings})
+	}
+
+	games, err := h.gameService.GetGamesByUserID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve games"})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="checkmait-export-%d.zip"`, userID))
+	c.Status(http.StatusOK)
+
+	// The status has been sent, so a failure part way through can only be
+	// logged; the client sees a truncated archive
+	if err := writeExport(c.Writer, []exportFile{
+		{name: "user.json", data: u},
+		{name: "models.json", data: models},
+		{name: "games.json", data: games},
+	}); err != nil {
+		logger := logging.FromContext(ctx)
+		logger.Error().Err(err).Int("user_id", userID).Msg("Failed to write data export")
+	}
+}
+
+// exportFile is one JSON file in a data export
+type exportFile struct {
+	name string
+	data any
+}
+
+// writeExport writes each file as indented JSON in a zip archive
+func writeExport(w io.Writer, files []exportFile) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
@@ -1,18 +0,0 @@
-package users
-
-import (
-	"net/http"
-
-	"github.com/gin-gonic/gin"
-)
-
-func (h *Handler) GetUserByID(c *gin.Context) {
-	id := c.Param("id")
-
-	user, err := h.userService.GetUserByID(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, err)
-		return
-	}
-	c.JSON(http.StatusOK, user)
-}
@@ -2,21 +2,36 @@ package users
 
 import (
 	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/services"
+	"github.com/ajlaz/checkmAIt/server/services/game"
+	"github.com/ajlaz/checkmAIt/server/services/limiter"
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
 	"github.com/ajlaz/checkmAIt/server/services/user"
+	"github.com/ajlaz/checkmAIt/server/services/user_model"
 )
 
 type Handler struct {
 	*api.API
 
-	userService user.ServiceInterface
-	jwtSecret   string
+	userService        user.ServiceInterface
+	modelService       user_model.ServiceInterface
+	gameService        game.ServiceInterface
+	matchmakingService matchmaking.ServiceInterface
+	accountLimiter     limiter.ServiceInterface
+	ipLimiter          limiter.ServiceInterface
+	jwtSecret          string
 }
 
-func NewHandler(a *api.API, userService user.ServiceInterface) *Handler {
+func NewHandler(a *api.API, svc services.Services) *Handler {
 	h := &Handler{
-		API:         a,
-		userService: userService,
-		jwtSecret:   a.GetJWTSecret(),
+		API:                a,
+		userService:        svc.UserService,
+		modelService:       svc.ModelService,
+		gameService:        svc.GameService,
+		matchmakingService: svc.MatchmakingService,
+		accountLimiter:     svc.AccountLimiter,
+		ipLimiter:          svc.IPLimiter,
+		jwtSecret:          a.GetJWTSecret(),
 	}
 
 	h.registerRoutes()
@@ -25,18 +40,32 @@ func NewHandler(a *api.API, userService user.ServiceInterface) *Handler {
 }
 
 func (h *Handler) registerRoutes() {
-	// Public routes
+	auth := api.JWTAuthMiddleware(h.jwtSecret, h.Sessions(), nil)
+	limit := h.RateLimit(api.RateLimitDefault)
+
+	// Public routes, rate limited per client IP
 	authGroup := h.Group("/auth")
+	authGroup.Use(h.RateLimit(api.RateLimitAuth))
 	{
 		authGroup.POST("/login", h.Login)
 		authGroup.POST("/register", h.Register)
+		authGroup.POST("/refresh", h.Refresh)
+		authGroup.POST("/logout", h.Logout)
+		authGroup.POST("/verify-email", h.VerifyEmail)
+		authGroup.POST("/verify-email/resend", auth, h.ResendVerification)
+		authGroup.POST("/password-reset", h.RequestPasswordReset)
+		authGroup.POST("/password-reset/confirm", h.ConfirmPasswordReset)
 	}
 
-	// Protected routes
-	// Add JWT middleware to users group
 	usersGroup := h.Group("/users")
-	usersGroup.Use(api.JWTAuthMiddleware(h.jwtSecret))
 	{
-		usersGroup.GET("/:id", h.GetUserByID)
+		// Public profiles
+		usersGroup.GET("/:id", limit, h.GetProfile)
+
+		// The authenticated user's own account
+		usersGroup.GET("/me", auth, limit, h.GetMe)
+		usersGroup.PATCH("/me", auth, limit, h.UpdateMe)
+		usersGroup.DELETE("/me", auth, limit, h.DeleteMe)
+		usersGroup.GET("/me/export", auth, limit, h.ExportMe)
 	}
 }
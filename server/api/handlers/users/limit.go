@@ -0,0 +1,127 @@
+package users
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/limiter"
+	"github.com/gin-gonic/gin"
+)
+
+// attempt identifies who is making a sensitive request for the limiters. An
+// empty account only limits by IP.
+type attempt struct {
+	account string
+	ip      string
+	settled bool // The attempt failed or succeeded and needs no forgiving
+
+	// Lockouts the attempt causes if it fails
+	ipLockout      *model.Lockout
+	accountLockout *model.Lockout
+}
+
+// newAttempt identifies a request by the account it targets and its client
+// IP. Emails are compared case-insensitively so case can't dodge the limit.
+func newAttempt(c *gin.Context, account string) *attempt {
+	return &attempt{
+		account: strings.ToLower(strings.TrimSpace(account)),
+		ip:      c.ClientIP(),
+	}
+}
+
+// allow responds with 429 and returns false if the account or IP is backing
+// off or locked out. Otherwise the limiters count the attempt as a failure
+// until it is settled, so callers must defer release.
+func (h *Handler) allow(c *gin.Context, a *attempt) bool {
+	ctx := c.Request.Context()
+
+	wait, lockout, err := h.ipLimiter.Attempt(ctx, a.ip)
+	a.ipLockout = lockout
+	if err == nil && wait == 0 && a.account != "" {
+		wait, a.accountLockout, err = h.accountLimiter.Attempt(ctx, a.account)
+		if err != nil || wait > 0 {
+			h.forgive(c, h.ipLimiter, a.ip)
+		}
+	}
+	if err != nil {
+		a.settled = true
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check login attempts",
+		})
+		return false
+	}
+
+	if wait > 0 {
+		a.settled = true
+		tooManyAttempts(c, wait)
+		return false
+	}
+	return true
+}
+
+// fail leaves the attempt counted as a failure against the account and IP
+// and records the lockouts it caused
+func (h *Handler) fail(c *gin.Context, a *attempt) {
+	a.settled = true
+	h.recordLockout(c, h.ipLimiter, a.ipLockout)
+	h.recordLockout(c, h.accountLimiter, a.accountLockout)
+}
+
+// succeed clears the account's failed attempts. The IP's earlier failures
+// are kept so one valid login can't reset a password-spraying client.
+func (h *Handler) succeed(c *gin.Context, a *attempt) {
+	a.settled = true
+	h.forgive(c, h.ipLimiter, a.ip)
+	if a.account == "" {
+		return
+	}
+	if err := h.accountLimiter.Reset(c.Request.Context(), a.account); err != nil {
+		logger := logging.FromContext(c.Request.Context())
+		logger.Error().Err(err).Msg("Failed to reset failed attempts for account")
+	}
+}
+
+// release forgives an attempt that neither failed nor succeeded, such as one
+// refused for a banned account or ended by an internal error
+func (h *Handler) release(c *gin.Context, a *attempt) {
+	if a.settled {
+		return
+	}
+	h.forgive(c, h.ipLimiter, a.ip)
+	if a.account != "" {
+		h.forgive(c, h.accountLimiter, a.account)
+	}
+}
+
+// forgive takes back an attempt counted against key. Errors are logged
+// rather than returned so the caller's response is unaffected.
+func (h *Handler) forgive(c *gin.Context, l limiter.ServiceInterface, key string) {
+	if err := l.Forgive(c.Request.Context(), key); err != nil {
+		logger := logging.FromContext(c.Request.Context())
+		logger.Error().Err(err).Msg("Failed to forgive login attempt")
+	}
+}
+
+// recordLockout records a lockout caused by a failed attempt, if any. Errors
+// are logged rather than returned so the caller's response is unaffected.
+func (h *Handler) recordLockout(c *gin.Context, l limiter.ServiceInterface, lockout *model.Lockout) {
+	if err := l.Fail(c.Request.Context(), lockout); err != nil {
+		logger := logging.FromContext(c.Request.Context())
+		logger.Error().Err(err).Msg("Failed to record lockout")
+	}
+}
+
+// tooManyAttempts responds with 429 and a Retry-After header
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed attempts, try again later",
+		"retry_after": seconds,
+	})
+}
@@ -0,0 +1,194 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/users"
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/user"
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateMeRequest struct {
+	Username        string `json:"username"`
+	Email           string `json:"email" binding:"omitempty,email"`
+	NewPassword     string `json:"new_password" binding:"omitempty,min=6"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+type DeleteMeRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// PublicModel is the part of a model shown on its owner's profile
+type PublicModel struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Rating   int      `json:"rating"`
+	Variants []string `json:"variants"`
+}
+
+// Profile is a user's public profile
+type Profile struct {
+	ID        int64           `json:"id"`
+	Username  string          `json:"username"`
+	CreatedAt string          `json:"created_at"`
+	Models    []PublicModel   `json:"models"`
+	Stats     model.UserStats `json:"stats"`
+}
+
+// GetProfile returns a user's public profile: their username, public models
+// and game results
+func (h *Handler) GetProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := c.Param("id")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	u, err := h.userService.GetUserByID(ctx, id)
+	if err != nil || u.Deleted() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	userModels, err := h.modelService.GetModelsByUserID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve models"})
+		return
+	}
+
+	stats, err := h.gameService.GetUserStats(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stats"})
+		return
+	}
+
+	profile := Profile{
+		ID:        u.ID,
+		Username:  u.Username,
+		CreatedAt: u.CreatedAt.Format("2006-01-02"),
+		Models:    []PublicModel{},
+		Stats:     *stats,
+	}
+	for _, m := range userModels {
+		if m.Public {
+			profile.Models = append(profile.Models, PublicModel{
+				ID:       m.ID,
+				Name:     m.Name,
+				Rating:   m.Rating,
+				Variants: m.Variants,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetMe returns the authenticated user's account
+func (h *Handler) GetMe(c *gin.Context) {
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
+		return
+	}
+
+	u, err := h.userService.GetUserByID(c.Request.Context(), strconv.Itoa(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// UpdateMe changes the authenticated user's username, email or password.
+// The current password is required for any change.
+func (h *Handler) UpdateMe(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
+		return
+	}
+
+	updated, err := h.userService.UpdateProfile(ctx, userID, c.GetString("sessionID"), user.ProfileUpdate{
+		Username:        req.Username,
+		Email:           req.Email,
+		NewPassword:     req.NewPassword,
+		CurrentPassword: req.CurrentPassword,
+	})
+	switch {
+	case errors.Is(err, user.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	case errors.Is(err, user.ErrReservedUsername):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is reserved"})
+		return
+	case errors.Is(err, users.ErrUserExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username or email already in use"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+		return
+	}
+
+	// A changed address has to be verified again
+	if !updated.EmailVerified() && req.Email != "" {
+		if err := h.userService.SendVerificationEmail(ctx, userID); err != nil {
+			logger := logging.FromContext(ctx)
+			logger.Warn().Err(err).Int("user_id", userID).Msg("Failed to send verification email")
+		}
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteMe deletes the authenticated user's account. The account is
+// anonymized rather than removed so the games it played stay intact.
+func (h *Handler) DeleteMe(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req DeleteMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	userID, ok := api.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user ID found"})
+		return
+	}
+
+	err := h.userService.DeleteAccount(ctx, userID, req.Password)
+	if errors.Is(err, user.ErrWrongPassword) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password is incorrect"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	// Take the user out of matchmaking; either call fails harmlessly if
+	// they weren't queued or playing
+	_ = h.matchmakingService.RemoveFromQueue(ctx, userID)
+	_ = h.matchmakingService.RemovePlayerFromMatch(ctx, userID)
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
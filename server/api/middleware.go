@@ -2,55 +2,115 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ajlaz/checkmAIt/server/metrics"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// TracingMiddleware starts a span for every request, continuing the trace
+// of an incoming W3C traceparent header. Probe and metrics requests are not
+// traced.
+func TracingMiddleware(serviceName string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/livez", "/readyz", "/health", "/ping", "/metrics":
+			return false
+		}
+		return true
+	}))
+}
+
+// MetricsMiddleware records the duration of every request by route and
+// status. Requests that match no route share the "unmatched" route label so
+// scanners can't create unbounded label values.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// BodyLimitMiddleware caps request bodies at limit bytes. Reading past the
+// limit fails, so oversized requests are rejected when they are parsed; see
+// BodyTooLarge.
+func BodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// BodyTooLarge reports whether err came from reading a request body past
+// the limit set by BodyLimitMiddleware
+func BodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// RateLimitMiddleware refuses requests over limiter's rate with 429 and a
+// Retry-After header. Requests are counted per user when the auth
+// middleware has already run, otherwise per client IP.
+func RateLimitMiddleware(group string, limiter ratelimit.ServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, ok := UserIDFromContext(c); ok {
+			key = "user:" + strconv.Itoa(userID)
+		}
+
+		allowed, wait := limiter.Allow(key)
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			metrics.RateLimitedRequests.WithLabelValues(group).Inc()
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Too many requests, try again in %d seconds", retryAfter)})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // CORSMiddleware handles Cross-Origin Resource Sharing
-func CORSMiddleware(corsOrigins, corsMethods, corsHeaders string) gin.HandlerFunc {
+func CORSMiddleware(corsOrigins, corsMethods, corsHeaders []string) gin.HandlerFunc {
+	allowMethods := strings.Join(corsMethods, ",")
+	allowHeaders := strings.Join(corsHeaders, ",")
+
 	return func(c *gin.Context) {
 		// Get the origin from the request header
 		requestOrigin := c.Request.Header.Get("Origin")
-
-		// Use the configured origins to check if the request origin is allowed
-		allowedOrigins := strings.Split(corsOrigins, ",")
 		allowOrigin := ""
 
 		// Check if the request origin is in the allowed origins list
-		if requestOrigin != "" {
-			for _, allowedOrigin := range allowedOrigins {
-				trimmedOrigin := strings.TrimSpace(allowedOrigin)
-				// Support wildcard or exact match
-				if trimmedOrigin == "*" || trimmedOrigin == requestOrigin {
-					allowOrigin = requestOrigin
-					break
-				}
-			}
+		if requestOrigin != "" && originAllowed(corsOrigins, requestOrigin) {
+			allowOrigin = requestOrigin
 		}
 
 		// If no origin matched, use the first allowed origin as fallback
 		// This handles cases where Origin header might not be sent
-		if allowOrigin == "" && len(allowedOrigins) > 0 {
-			firstOrigin := strings.TrimSpace(allowedOrigins[0])
-			if firstOrigin == "*" {
-				allowOrigin = "*"
-			} else {
-				allowOrigin = firstOrigin
-			}
-		}
-
-		// Use the configured methods and headers
-		allowMethods := corsMethods
-		if allowMethods == "" {
-			allowMethods = "GET,POST,PUT,PATCH,DELETE,OPTIONS"
-		}
-
-		allowHeaders := corsHeaders
-		if allowHeaders == "" {
-			allowHeaders = "Content-Type,Content-Length,Accept-Encoding,X-CSRF-Token,Authorization,accept,origin,Cache-Control,X-Requested-With"
+		if allowOrigin == "" && len(corsOrigins) > 0 {
+			allowOrigin = corsOrigins[0]
 		}
 
 		// Set the CORS headers
@@ -69,9 +129,29 @@ func CORSMiddleware(corsOrigins, corsMethods, corsHeaders string) gin.HandlerFun
 	}
 }
 
-// JWTAuthMiddleware validates JWT tokens and sets user claims in context
-func JWTAuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// originAllowed reports whether origin matches an entry in corsOrigins, where
+// "*" matches any origin
+func originAllowed(corsOrigins []string, origin string) bool {
+	for _, allowedOrigin := range corsOrigins {
+		// Support wildcard or exact match
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTAuthMiddleware validates JWT tokens, rejects tokens whose session has
+// been revoked and sets user claims in context. If apiKeys is not nil, a
+// personal API key is accepted instead, either as the Bearer token or in the
+// X-API-Key header; use RequireScope to restrict what such keys can reach.
+func JWTAuthMiddleware(jwtSecret string, sessions SessionChecker, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" && apiKeys != nil {
+			authenticateAPIKey(c, key, apiKeys)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -88,35 +168,185 @@ func JWTAuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		// Extract the token
-		tokenString := parts[1]
-
-		// Parse and validate the token
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate the alg is what we expect
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(jwtSecret), nil
-		})
+		authenticateCredential(c, parts[1], jwtSecret, sessions, apiKeys)
+	}
+}
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+// JWTQueryAuthMiddleware behaves like JWTAuthMiddleware but also accepts the
+// token in the access_token query parameter. Browsers cannot set headers on
+// EventSource or WebSocket connections, so streaming endpoints use this.
+func JWTQueryAuthMiddleware(jwtSecret string, sessions SessionChecker, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
+	headerAuth := JWTAuthMiddleware(jwtSecret, sessions, apiKeys)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" || c.GetHeader("X-API-Key") != "" {
+			headerAuth(c)
 			return
 		}
 
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		tokenString := c.Query("access_token")
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header or access_token query parameter is required"})
 			c.Abort()
 			return
 		}
 
-		// Set the user claims in the context
-		c.Set("userID", claims["user_id"])
-		c.Set("username", claims["username"])
-		c.Set("email", claims["email"])
+		authenticateCredential(c, tokenString, jwtSecret, sessions, apiKeys)
+	}
+}
+
+// RequireScope restricts a route to requests authenticated with an API key
+// granted scope. Requests authenticated with a login session are always let
+// through.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopesVal, isAPIKey := c.Get("apiKeyScopes")
+		if !isAPIKey {
+			c.Next()
+			return
+		}
+
+		scopes, _ := scopesVal.([]string)
+		for _, s := range scopes {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing the " + scope + " scope"})
+		c.Abort()
+	}
+}
+
+// RequireRole restricts a route to users whose role grants at least role.
+// The role is read from the access token, so it must run after
+// JWTAuthMiddleware. Requests authenticated with an API key are refused.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRole, _ := c.Get("role")
+		if r, ok := userRole.(string); !ok || !model.RoleAtLeast(r, role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Requires the " + role + " role"})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// RoleFromContext returns the authenticated user's role set by the JWT
+// middleware
+func RoleFromContext(c *gin.Context) string {
+	role, _ := c.Get("role")
+	r, _ := role.(string)
+	return r
+}
+
+// authenticateCredential authenticates a Bearer credential, which is either
+// a JWT or, if apiKeys is not nil, a personal API key
+func authenticateCredential(c *gin.Context, credential, jwtSecret string, sessions SessionChecker, apiKeys APIKeyAuthenticator) {
+	if apiKeys != nil && strings.HasPrefix(credential, model.APIKeyPrefix) {
+		authenticateAPIKey(c, credential, apiKeys)
+		return
+	}
+
+	authenticateToken(c, credential, jwtSecret, sessions)
+}
+
+// authenticateAPIKey validates a personal API key and sets its owner and
+// scopes in context, aborting the request if the key is not acceptable
+func authenticateAPIKey(c *gin.Context, key string, apiKeys APIKeyAuthenticator) {
+	apiKey, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked API key"})
+		c.Abort()
+		return
+	}
+
+	// Stored as float64 to match user IDs taken from JWT claims
+	c.Set("userID", float64(apiKey.UserID))
+	c.Set("apiKeyID", apiKey.ID)
+	c.Set("apiKeyScopes", []string(apiKey.Scopes))
+	attachUser(c)
+
+	c.Next()
+}
+
+// authenticateToken parses and validates the token and sets the user claims
+// in context, aborting the request if the token is not acceptable
+func authenticateToken(c *gin.Context, tokenString, jwtSecret string, sessions SessionChecker) {
+	// Parse and validate the token
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Validate the alg is what we expect
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(jwtSecret), nil
+	})
+
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return
+	}
+
+	if !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+
+	// Tokens are only valid while the session they were issued under is still
+	// active
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+
+	revoked, err := sessions.IsSessionRevoked(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+		c.Abort()
+		return
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		c.Abort()
+		return
+	}
+
+	// Set the user claims in the context
+	c.Set("sessionID", sessionID)
+	c.Set("userID", claims["user_id"])
+	c.Set("username", claims["username"])
+	c.Set("email", claims["email"])
+	c.Set("role", claims["role"])
+	attachUser(c)
+
+	c.Next()
+}
+
+// UserIDFromContext returns the authenticated user's ID set by the auth middleware
+func UserIDFromContext(c *gin.Context) (int, bool) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		return 0, false
+	}
+
+	// JWT claims are stored as float64 when unmarshalled
+	switch v := userIDVal.(type) {
+	case float64:
+		return int(v), true
+	case string:
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, false
+		}
+		return userID, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID that ties together the logs of a request
+// across the server and the engine
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// RequestLogger assigns every request an ID, reusing a well-formed
+// X-Request-ID from the client, and echoes it in the response. It attaches a
+// logger carrying the ID and route to the request context, and writes one
+// access log line when the request completes.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		logCtx := logging.FromContext(c.Request.Context()).With().Str("request_id", id).Str("route", route)
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			logCtx = logCtx.Str("trace_id", span.TraceID().String())
+		}
+		logger := logCtx.Logger()
+		ctx := logging.WithRequestID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(logging.WithContext(logger, ctx))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		logger = logging.FromContext(c.Request.Context())
+		event := logger.Info()
+		switch {
+		case status >= 500:
+			event = logger.Error()
+		case status >= 400:
+			event = logger.Warn()
+		}
+
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+			event = event.Str("errors", errs)
+		}
+
+		event.
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Int("bytes", max(c.Writer.Size(), 0)).
+			Float64("latency_ms", float64(time.Since(start).Microseconds())/1000).
+			Str("client_ip", c.ClientIP()).
+			Str("user_agent", c.Request.UserAgent()).
+			Msg("request")
+	}
+}
+
+// attachUser adds the authenticated user's ID to the request's logger
+func attachUser(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	logger := logging.FromContext(ctx).With().Int("user_id", userID).Logger()
+	c.Request = c.Request.WithContext(logging.WithContext(logger, ctx))
+}
+
+// validRequestID reports whether id is a reasonable length and made only of
+// characters that are safe to log and forward
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
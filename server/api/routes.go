@@ -3,12 +3,15 @@ package api
 import (
 	"net/http"
 
+	"github.com/ajlaz/checkmAIt/server/metrics"
+
 	"github.com/gin-gonic/gin"
 )
 
 func (a *API) RegisterDefaultRoutes() {
 	a.GET("/health", a.healthCheck)
 	a.GET("/ping", a.ping)
+	a.GET("/metrics", gin.WrapH(metrics.Handler()))
 }
 
 // default routes
@@ -2,24 +2,37 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/ajlaz/checkmAIt/server/cmd/config"
 	"github.com/ajlaz/checkmAIt/server/cmd/migrate"
 	"github.com/ajlaz/checkmAIt/server/cmd/server"
+	cfg "github.com/ajlaz/checkmAIt/server/config"
 	"github.com/spf13/cobra"
 )
 
 var RootCmd = &cobra.Command{
 	Use:   "base",
 	Short: "Base command for the application",
+
+	// Errors are printed once by Execute, without the usage text
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
 func init() {
+	flags := RootCmd.PersistentFlags()
+	flags.StringVar(&cfg.Flags.File, "config", "", "settings file in .env format (default \""+cfg.DefaultFile+"\" if present)")
+	flags.StringArrayVar(&cfg.Flags.Overrides, "set", nil, "override a setting, as KEY=VALUE; may be repeated")
+
 	RootCmd.AddCommand(server.ServerCmd)
 	RootCmd.AddCommand(migrate.MigrateCmd)
+	RootCmd.AddCommand(config.ConfigCmd)
 }
@@ -0,0 +1,14 @@
+package config
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+func init() {
+	ConfigCmd.AddCommand(PrintCmd)
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ajlaz/checkmAIt/server/config"
+	"github.com/spf13/cobra"
+)
+
+var PrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration",
+	Long: `Print every setting with its effective value and where it came from:
+the default, the settings file, the environment or a --set flag. Secrets are
+redacted. Exits non-zero if the configuration is invalid.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printConfig()
+	},
+}
+
+func printConfig() error {
+	cfg, err := config.Read(config.Flags)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, setting := range cfg.Settings() {
+		fmt.Fprintf(w, "%s\t%s=%s\n", setting.Source, setting.Key, setting.Display())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return cfg.Validate()
+}
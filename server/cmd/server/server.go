@@ -4,22 +4,33 @@ import (
 	"context"
 	"os"
 	"os/signal"
-	"time"
+	"syscall"
 
 	"github.com/ajlaz/checkmAIt/server/api"
+	"github.com/ajlaz/checkmAIt/server/api/handlers/admin"
+	"github.com/ajlaz/checkmAIt/server/api/handlers/apikeys"
+	"github.com/ajlaz/checkmAIt/server/api/handlers/games"
+	healthhandler "github.com/ajlaz/checkmAIt/server/api/handlers/health"
 	"github.com/ajlaz/checkmAIt/server/api/handlers/matchmaking"
 	"github.com/ajlaz/checkmAIt/server/api/handlers/models"
 	"github.com/ajlaz/checkmAIt/server/api/handlers/users"
+	"github.com/ajlaz/checkmAIt/server/cmd/migrate"
 	"github.com/ajlaz/checkmAIt/server/config"
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/metrics"
 	"github.com/ajlaz/checkmAIt/server/server"
 	"github.com/ajlaz/checkmAIt/server/services"
+	"github.com/ajlaz/checkmAIt/server/services/health"
+	"github.com/ajlaz/checkmAIt/server/tracing"
 	"github.com/rs/zerolog"
 )
 
-var logger zerolog.Logger
+// logger is replaced by the configured logger once the config is loaded
+var logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
 
 func Run() {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	// Docker and Kubernetes stop containers with SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	// setup server
@@ -28,40 +39,106 @@ func Run() {
 		logger.Fatal().Err(err).Msg("Failed to load config")
 	}
 
-	srv := server.New(cfg)
+	logger, err = logging.New(logging.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to set up logging")
+	}
+	ctx = logging.WithContext(logger, ctx)
+
+	// Tracing is set up before anything that is instrumented
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		ServiceName:  cfg.Tracing.ServiceName,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
 
 	store := initStore(cfg)
 
-	services := services.NewServices(store.user_store, store.model_store, cfg.Engine.URL)
+	services, err := services.NewServices(cfg, store.user_store, store.model_store, store.game_store, store.session_store, store.apikey_store, store.limiter_store, store.matchmaking_store)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to initialize services")
+	}
+	if err := services.MatchmakingService.Restore(ctx); err != nil {
+		logger.Fatal().Err(err).Msg("Failed to restore matchmaking state")
+	}
+	metrics.RegisterDB(store.db.DB, "postgres")
+	metrics.RegisterMatchmaking(services.MatchmakingService.GetQueueStats)
+
+	// Background workers keep running while matchmaking drains, so they are
+	// only stopped once the HTTP server has shut down
+	workers := newWorkers(context.WithoutCancel(ctx))
+	workers.Go(services.EngineService.StartHealthChecks)
+	workers.Go(services.MatchmakingService.StartReaper)
+	workers.Go(services.MatchmakingService.StartEventRelay)
+	workers.Go(services.MatchmakingService.DeleteOrphanGames)
 
-	a := api.New(cfg)
+	readinessChecks := []health.Check{
+		health.Database(store.db.DB),
+		health.Engine(services.EngineService),
+		health.Migrations(store.db.DB, migrate.MigrationsDir),
+	}
+	if store.redis != nil {
+		readinessChecks = append(readinessChecks, health.Redis(store.redis))
+	}
+	healthService := health.NewService(health.Config{
+		Timeout:  cfg.Health.CheckTimeout,
+		CacheTTL: cfg.Health.CacheTTL,
+	}, readinessChecks...)
+
+	a, err := api.New(cfg, services.UserService, services.APIKeyService)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to set up API")
+	}
 	// initialize handlers
-	_ = users.NewHandler(a, services.UserService)
+	_ = healthhandler.NewHandler(a, healthService)
+	_ = users.NewHandler(a, *services)
+	_ = apikeys.NewHandler(a, services.APIKeyService)
+	_ = admin.NewHandler(a, services.UserService, services.ModelService, services.MatchmakingService, services.AccountLimiter)
 	_ = models.NewHandler(a, services.UserService, services.ModelService)
 	_ = matchmaking.NewHandler(a, *services)
+	_ = games.NewHandler(a, *services)
 
-	idleConnsClosed := make(chan struct{})
-	// gracefully shutdown the server on os.interrupt signal
+	srv := server.New(cfg, a)
+	serverStopped := make(chan struct{})
 	go func() {
-		const shutdownTimeout = 5 * time.Second
-
-		sigint := make(chan os.Signal, 1)
-		signal.Notify(sigint, os.Interrupt)
-		<-sigint
-
-		ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
-		defer cancel()
-
-		if err := srv.Shutdown(ctx); err != nil {
-			logger.Fatal().Err(err).Msg("Server Shutdown Failed")
-		}
-		logger.Info().Ctx(ctx).Msg("HTTP server stopped")
-		close(idleConnsClosed)
+		defer close(serverStopped)
+		logger.Info().Ctx(ctx).Msg("Starting server...")
+		srv.Run(ctx)
 	}()
 
-	logger.Info().Ctx(ctx).Msg("Starting server...")
-	srv.Run(ctx, a)
+	select {
+	case <-ctx.Done():
+		logger.Info().Msg("Shutdown signal received")
+	case <-serverStopped:
+		logger.Error().Msg("HTTP server stopped unexpectedly, shutting down")
+	}
+	// A second signal kills the process without waiting for the shutdown
+	stop()
 
-	<-idleConnsClosed
-	logger.Info().Ctx(ctx).Msg("Server exited gracefully")
+	shutdown(ctx, cfg.HTTP.ShutdownTimeout,
+		shutdownStep{"stop matchmaking joins and close queues", services.MatchmakingService.Drain},
+		shutdownStep{"stop HTTP server", srv.Shutdown},
+		// Active matches are saved as they change, to Postgres by the memory
+		// store or in Redis, so what is left only needs reporting
+		shutdownStep{"report matches kept for restart", func(ctx context.Context) error {
+			queued, active := services.MatchmakingService.GetQueueStats(ctx)
+			logger.Info().
+				Str("store", cfg.Matchmaking.Store).
+				Int("active_matches", active).
+				Int("queued_players", queued).
+				Msg("Matchmaking state left in the store")
+			return nil
+		}},
+		shutdownStep{"stop background workers", workers.Stop},
+		shutdownStep{"flush traces", shutdownTracing},
+		shutdownStep{"close connections", func(context.Context) error {
+			return store.close()
+		}},
+	)
+	logger.Info().Msg("Server exited gracefully")
 }
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+)
+
+// shutdownStep is one stage of shutting the server down
+type shutdownStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// shutdown runs the steps in order under one overall deadline, logging each.
+// A failed step doesn't stop the ones after it; once the deadline passes the
+// remaining steps get an expired context and give up quickly.
+func shutdown(ctx context.Context, timeout time.Duration, steps ...shutdownStep) {
+	logger := logging.FromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	start := time.Now()
+	logger.Info().Dur("timeout", timeout).Msg("Shutting down")
+
+	for _, step := range steps {
+		stepStart := time.Now()
+		logger.Info().Str("step", step.name).Msg("Shutdown step started")
+
+		if err := step.run(ctx); err != nil {
+			logger.Error().Err(err).Str("step", step.name).Dur("duration", time.Since(stepStart)).Msg("Shutdown step failed")
+			continue
+		}
+		logger.Info().Str("step", step.name).Dur("duration", time.Since(stepStart)).Msg("Shutdown step finished")
+	}
+
+	logger.Info().Dur("duration", time.Since(start)).Msg("Shutdown complete")
+}
+
+// workers runs background loops that stop when their context is cancelled
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkers(ctx context.Context) *workers {
+	ctx, cancel := context.WithCancel(ctx)
+	return &workers{ctx: ctx, cancel: cancel}
+}
+
+// Go runs a worker in its own goroutine
+func (w *workers) Go(run func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		run(w.ctx)
+	}()
+}
+
+// Stop cancels every worker and waits for them to return, or for ctx to end
+func (w *workers) Stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
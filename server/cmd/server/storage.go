@@ -1,22 +1,69 @@
 package server
 
 import (
+	"errors"
+
 	"github.com/ajlaz/checkmAIt/server/config"
 	"github.com/ajlaz/checkmAIt/server/db/store/postgres"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/apikeys"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/games"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/limiter"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/matches"
 	"github.com/ajlaz/checkmAIt/server/db/store/postgres/models"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/sessions"
 	"github.com/ajlaz/checkmAIt/server/db/store/postgres/users"
+	redisstore "github.com/ajlaz/checkmAIt/server/db/store/redis"
+	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
 )
 
 type store struct {
+	db *sqlx.DB
+
 	user_store  users.StoreInterface
 	model_store models.StoreInterface
+	game_store  games.StoreInterface
+
+	session_store sessions.StoreInterface
+	apikey_store  apikeys.StoreInterface
+	limiter_store limiter.StoreInterface
+
+	redis             *redis.Client // Only connected when matchmaking state is kept in Redis
+	matchmaking_store matchmaking.StoreInterface
 }
 
 func initStore(cfg *config.Config) *store {
 	db := postgres.Connect(cfg)
 
+	var redisClient *redis.Client
+	matchmakingStore := matchmaking.NewMemoryStore(matches.NewStore(db, cfg.Postgres.QueryTimeout))
+	if cfg.Matchmaking.Store == matchmaking.StoreRedis {
+		redisClient = redisstore.Connect(cfg)
+		matchmakingStore = matchmaking.NewRedisStore(redisClient, cfg.Redis.KeyPrefix)
+	}
+
 	return &store{
-		user_store:  users.NewStore(db),
-		model_store: models.NewStore(db),
+		db: db,
+
+		user_store:  users.NewStore(db, cfg.Postgres.QueryTimeout),
+		model_store: models.NewStore(db, cfg.Postgres.QueryTimeout),
+		game_store:  games.NewStore(db, cfg.Postgres.QueryTimeout),
+
+		session_store: sessions.NewStore(db, cfg.Postgres.QueryTimeout),
+		apikey_store:  apikeys.NewStore(db, cfg.Postgres.QueryTimeout),
+		limiter_store: limiter.NewStore(db, cfg.Postgres.QueryTimeout),
+
+		redis:             redisClient,
+		matchmaking_store: matchmakingStore,
+	}
+}
+
+// close closes the database and, if connected, the Redis client
+func (s *store) close() error {
+	errs := []error{s.db.Close()}
+	if s.redis != nil {
+		errs = append(errs, s.redis.Close())
 	}
+	return errors.Join(errs...)
 }
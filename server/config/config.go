@@ -1,47 +1,132 @@
 package config
 
-import "github.com/joho/godotenv"
+import (
+	"strings"
+	"time"
+)
 
 type Config struct {
-	Postgres PostgresConfig
-	HTTP     HTTPConfig
-	Auth     AuthConfig
-	Engine   EngineConfig
-}
+	Postgres     PostgresConfig
+	Redis        RedisConfig
+	HTTP         HTTPConfig
+	Auth         AuthConfig
+	Engine       EngineConfig
+	Matchmaking  MatchmakingConfig
+	Adjudication AdjudicationConfig
+	Openings     OpeningsConfig
+	Mail         MailConfig
+	LoginLimit   LoginLimitConfig
+	RateLimit    RateLimitConfig
+	Quotas       QuotaConfig
+	Health       HealthConfig
+	Log          LogConfig
+	Tracing      TracingConfig
 
-var env map[string]string
+	settings []Setting
+	problems []string // Values that could not be parsed
+}
 
+// LoadConfig reads and validates the configuration from the sources selected
+// by the command-line flags
 func LoadConfig() (*Config, error) {
+	return Load(Flags)
+}
+
+// Load reads the configuration and validates it, reporting every invalid
+// setting at once
+func Load(opts Options) (*Config, error) {
+	cfg, err := Read(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-	env, err := godotenv.Read()
+// Read reads the configuration from, in increasing order of precedence, the
+// defaults, the settings file, the environment and the command-line
+// overrides. Invalid values are reported by Validate.
+func Read(opts Options) (*Config, error) {
+	r, err := newReader(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	config := &Config{
+	cfg := &Config{
 		Postgres: PostgresConfig{
-			Host:     env["POSTGRES_HOST"],
-			User:     env["POSTGRES_USER"],
-			Password: env["POSTGRES_PASSWORD"],
-			DB:       env["POSTGRES_DB"],
-			Port:     env["POSTGRES_PORT"],
+			Host:     r.String("POSTGRES_HOST", "postgres"),
+			User:     r.String("POSTGRES_USER", "postgres"),
+			Password: r.Secret("POSTGRES_PASSWORD", "postgres"),
+			DB:       r.String("POSTGRES_DB", "postgres"),
+			Port:     r.Port("POSTGRES_PORT", 5432),
+
+			QueryTimeout: r.Duration("POSTGRES_QUERY_TIMEOUT", 5*time.Second),
 		},
-		HTTP: HTTPConfig{
-			Host:        env["HTTP_HOST"],
-			Port:        env["HTTP_PORT"],
-			CORSOrigins: env["HTTP_CORS_ORIGINS"],
-			CORSMethods: env["HTTP_CORS_METHODS"],
-			CORSHeaders: env["HTTP_CORS_HEADERS"],
+		Redis: RedisConfig{
+			URL:       r.Secret("REDIS_URL", "redis://redis:6379/0"),
+			KeyPrefix: r.String("REDIS_KEY_PREFIX", "checkmait:"),
 		},
-		Auth: AuthConfig{
-			JWTSecret: env["JWT_SECRET"],
+		HTTP: HTTPConfig{
+			Host:        r.String("HTTP_HOST", "0.0.0.0"),
+			Port:        r.Port("HTTP_PORT", 8080),
+			CORSOrigins: r.List("HTTP_CORS_ORIGINS", []string{"http://localhost:5173"}),
+			CORSMethods: r.List("HTTP_CORS_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			CORSHeaders: r.List("HTTP_CORS_HEADERS", []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"}),
+
+			TrustedProxies: r.List("HTTP_TRUSTED_PROXIES", nil),
+
+			MaxBodyBytes:    r.Int("HTTP_MAX_BODY_BYTES", 1<<20),
+			ShutdownTimeout: r.Duration("SHUTDOWN_TIMEOUT", 25*time.Second),
 		},
+		Auth: loadAuthConfig(r),
 		Engine: EngineConfig{
-			URL: env["ENGINE_URL"],
+			URL:  r.String("ENGINE_URL", "http://engine:3000"),
+			URLs: r.List("ENGINE_URLS", nil),
+
+			RequestTimeout: r.Duration("ENGINE_REQUEST_TIMEOUT", 10*time.Second),
+		},
+		Matchmaking:  loadMatchmakingConfig(r),
+		Adjudication: loadAdjudicationConfig(r),
+		Openings: OpeningsConfig{
+			Dir: r.String("OPENINGS_DIR", ""),
+		},
+		Mail:       loadMailConfig(r),
+		LoginLimit: loadLoginLimitConfig(r),
+		RateLimit:  loadRateLimitConfig(r),
+		Quotas: QuotaConfig{
+			MaxModelsPerUser: r.Int("MAX_MODELS_PER_USER", 20),
+			MaxCodeBytes:     r.Int("MAX_MODEL_CODE_BYTES", 256<<10),
+			MaxGamesPerDay:   r.Int("MAX_GAMES_PER_DAY", 500),
+		},
+		Log: LogConfig{
+			Level:  r.OneOf("LOG_LEVEL", "info", "trace", "debug", "info", "warn", "error"),
+			Format: r.OneOf("LOG_FORMAT", "json", "json", "console"),
+		},
+		Tracing: TracingConfig{
+			Exporter:     r.OneOf("TRACING_EXPORTER", "none", "none", "otlp", "stdout"),
+			ServiceName:  r.String("TRACING_SERVICE_NAME", "checkmait-server"),
+			OTLPEndpoint: r.String("TRACING_OTLP_ENDPOINT", "localhost:4318"),
+			OTLPInsecure: r.Bool("TRACING_OTLP_INSECURE", false),
+			SampleRatio:  r.Fraction("TRACING_SAMPLE_RATIO", 1),
+		},
+		Health: HealthConfig{
+			CheckTimeout: r.Duration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			CacheTTL:     r.Duration("HEALTH_CACHE_TTL", 2*time.Second),
 		},
 	}
 
-	return config, nil
+	cfg.settings = r.settings
+	cfg.problems = r.problems
+
+	return cfg, nil
+}
+
+// Settings returns every setting that was read, in the order it was read,
+// with its effective value and where that value came from
+func (c *Config) Settings() []Setting {
+	return c.settings
 }
 
 type PostgresConfig struct {
@@ -49,21 +134,204 @@ type PostgresConfig struct {
 	User     string
 	Password string
 	DB       string
-	Port     string
+	Port     int
+
+	QueryTimeout time.Duration // Deadline for each store call, including transactions
+}
+
+type RedisConfig struct {
+	URL       string // redis:// or rediss:// URL, including any password
+	KeyPrefix string // Prepended to every key, so deployments can share a database
 }
 
 type HTTPConfig struct {
 	Host        string
-	Port        string
-	CORSOrigins string // Comma-separated list of allowed origins
-	CORSMethods string // Comma-separated list of allowed HTTP methods
-	CORSHeaders string // Comma-separated list of allowed HTTP headers
+	Port        int
+	CORSOrigins []string // Allowed origins, where "*" allows any
+	CORSMethods []string // Allowed HTTP methods
+	CORSHeaders []string // Allowed HTTP headers
+
+	// Proxy IPs and CIDRs whose X-Forwarded-For header is believed when
+	// finding a client's IP; none are trusted by default
+	TrustedProxies []string
+
+	MaxBodyBytes    int           // Largest request body accepted
+	ShutdownTimeout time.Duration // Bounds the whole shutdown sequence
 }
 
 type AuthConfig struct {
-	JWTSecret string
+	JWTSecret            string
+	AccessTokenTTL       time.Duration // Lifetime of access tokens
+	RefreshTokenTTL      time.Duration // Lifetime of each refresh token
+	VerificationTokenTTL time.Duration // Lifetime of email verification links
+	PasswordResetTTL     time.Duration // Lifetime of password reset links
+}
+
+func loadAuthConfig(r *reader) AuthConfig {
+	return AuthConfig{
+		JWTSecret:            r.Secret("JWT_SECRET", ""),
+		AccessTokenTTL:       r.Duration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:      r.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		VerificationTokenTTL: r.Duration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		PasswordResetTTL:     r.Duration("PASSWORD_RESET_TTL", time.Hour),
+	}
+}
+
+type MailConfig struct {
+	Driver       string // "smtp", "outbox" or "log"
+	From         string
+	AppURL       string // Base URL of the frontend, used in emailed links
+	OutboxDir    string // Where the outbox driver writes messages
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
+
+func loadMailConfig(r *reader) MailConfig {
+	return MailConfig{
+		Driver:       r.OneOf("MAIL_DRIVER", "log", "smtp", "outbox", "log"),
+		From:         r.String("MAIL_FROM", "checkmAIt <no-reply@localhost>"),
+		AppURL:       strings.TrimRight(r.String("APP_URL", "http://localhost:5173"), "/"),
+		OutboxDir:    r.String("MAIL_OUTBOX_DIR", "outbox"),
+		SMTPHost:     r.String("SMTP_HOST", ""),
+		SMTPPort:     r.Port("SMTP_PORT", 587),
+		SMTPUsername: r.String("SMTP_USERNAME", ""),
+		SMTPPassword: r.Secret("SMTP_PASSWORD", ""),
+	}
+}
+
+type LoginLimitConfig struct {
+	Backend                string        // "memory" or "postgres"
+	AccountFreeAttempts    int           // Failed logins per account before backoff starts
+	AccountLockoutAttempts int           // Failed logins that lock an account out; 0 disables
+	IPFreeAttempts         int           // Failed logins per client IP before backoff starts
+	IPLockoutAttempts      int           // Failed logins that lock an IP out; 0 disables
+	BackoffBase            time.Duration // First backoff wait, doubling per further failure
+	BackoffMax             time.Duration // Longest backoff wait
+	LockoutDuration        time.Duration // How long a lockout lasts
+	Window                 time.Duration // Failures are forgotten after this long without another
+}
+
+func loadLoginLimitConfig(r *reader) LoginLimitConfig {
+	return LoginLimitConfig{
+		Backend:                r.OneOf("LOGIN_LIMIT_BACKEND", "memory", "memory", "postgres"),
+		AccountFreeAttempts:    r.Int("LOGIN_ACCOUNT_FREE_ATTEMPTS", 5),
+		AccountLockoutAttempts: r.Int("LOGIN_ACCOUNT_LOCKOUT_ATTEMPTS", 10),
+		IPFreeAttempts:         r.Int("LOGIN_IP_FREE_ATTEMPTS", 20),
+		IPLockoutAttempts:      r.Int("LOGIN_IP_LOCKOUT_ATTEMPTS", 100),
+		BackoffBase:            r.Duration("LOGIN_BACKOFF_BASE", time.Second),
+		BackoffMax:             r.Duration("LOGIN_BACKOFF_MAX", time.Minute),
+		LockoutDuration:        r.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		Window:                 r.Duration("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+}
+
+// RatePolicy is a token bucket refilled at PerMinute requests a minute that
+// holds up to Burst requests
+type RatePolicy struct {
+	PerMinute int // Sustained requests per minute; 0 disables the limit
+	Burst     int // Requests allowed at once
+}
+
+// RateLimitConfig holds the request limits of each route group. Requests
+// are counted per user once authenticated, otherwise per client IP.
+type RateLimitConfig struct {
+	Auth        RatePolicy // /auth
+	Models      RatePolicy // /models
+	Matchmaking RatePolicy // /matchmaking
+	Default     RatePolicy // Every other group
+}
+
+func loadRateLimitConfig(r *reader) RateLimitConfig {
+	policy := func(group string, perMinute, burst int) RatePolicy {
+		return RatePolicy{
+			PerMinute: r.Int("RATE_LIMIT_"+group+"_PER_MINUTE", perMinute),
+			Burst:     r.Int("RATE_LIMIT_"+group+"_BURST", burst),
+		}
+	}
+
+	return RateLimitConfig{
+		Auth:        policy("AUTH", 20, 10),
+		Models:      policy("MODELS", 60, 20),
+		Matchmaking: policy("MATCHMAKING", 30, 10),
+		Default:     policy("DEFAULT", 120, 40),
+	}
+}
+
+type QuotaConfig struct {
+	MaxModelsPerUser int // Models each user may own; 0 disables
+	MaxCodeBytes     int // Largest model code accepted; 0 disables
+	MaxGamesPerDay   int // Completed games each user may play in 24 hours before joining is refused; 0 disables
 }
 
 type EngineConfig struct {
-	URL string
+	URL  string
+	URLs []string // Engine instances; overrides URL when set
+
+	RequestTimeout time.Duration // Deadline for each call to an engine
+}
+
+// Endpoints returns the configured engine instances
+func (e EngineConfig) Endpoints() []string {
+	if len(e.URLs) == 0 {
+		return []string{e.URL}
+	}
+	return e.URLs
+}
+
+type LogConfig struct {
+	Level  string // "trace", "debug", "info", "warn" or "error"
+	Format string // "json" or "console"
+}
+
+type TracingConfig struct {
+	Exporter     string  // "none" (tracing off), "otlp" or "stdout"
+	ServiceName  string  // Service name spans are reported under
+	OTLPEndpoint string  // host:port of the OTLP/HTTP collector
+	OTLPInsecure bool    // Send spans to the collector without TLS
+	SampleRatio  float64 // Fraction of new traces sampled
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration // Bounds each readiness check
+	CacheTTL     time.Duration // How long readiness results are reused
+}
+
+type OpeningsConfig struct {
+	Dir string // Directory of .epd and .pgn opening suites
+}
+
+type MatchmakingConfig struct {
+	Store          string        // "memory" (single instance) or "redis" (shared by every instance)
+	ConnectTimeout time.Duration // How long a match may wait for both players to connect
+	GameTimeout    time.Duration // How long an untimed game may stay in progress
+	ReapInterval   time.Duration // How often match deadlines and clocks are checked
+	ReconnectGrace time.Duration // How long a disconnected player has to return
+}
+
+func loadMatchmakingConfig(r *reader) MatchmakingConfig {
+	return MatchmakingConfig{
+		Store:          r.OneOf("MATCHMAKING_STORE", "memory", "memory", "redis"),
+		ConnectTimeout: r.Duration("MATCH_CONNECT_TIMEOUT", 2*time.Minute),
+		GameTimeout:    r.Duration("MATCH_GAME_TIMEOUT", time.Hour),
+		ReapInterval:   r.Duration("MATCH_REAP_INTERVAL", time.Second),
+		ReconnectGrace: r.Duration("MATCH_RECONNECT_GRACE", time.Minute),
+	}
+}
+
+type AdjudicationConfig struct {
+	MoveCap              int  // Full moves after which a game is drawn; 0 disables
+	InsufficientMaterial bool // Draw when neither side has mating material
+	MaterialThreshold    int  // Material lead in pawns that wins a game; 0 disables
+	MaterialPlies        int  // Consecutive plies the lead must be held
+}
+
+func loadAdjudicationConfig(r *reader) AdjudicationConfig {
+	return AdjudicationConfig{
+		MoveCap:              r.Int("ADJUDICATION_MOVE_CAP", 200),
+		InsufficientMaterial: r.Bool("ADJUDICATION_INSUFFICIENT_MATERIAL", true),
+		MaterialThreshold:    r.Int("ADJUDICATION_MATERIAL_THRESHOLD", 0),
+		MaterialPlies:        r.Int("ADJUDICATION_MATERIAL_PLIES", 20),
+	}
 }
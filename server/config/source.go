@@ -0,0 +1,257 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// DefaultFile is the settings file read when no other file is given. Unlike
+// a file passed with --config, it may be missing.
+const DefaultFile = ".env"
+
+// Where a setting's value came from, lowest precedence first
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// redacted replaces the value of a secret setting when it is printed
+const redacted = "********"
+
+// Options selects where settings are read from besides the environment
+type Options struct {
+	File      string   // Settings file in .env format; defaults to DefaultFile
+	Overrides []string // KEY=VALUE pairs from the command line, applied last
+}
+
+// Flags holds the options set by the root command's --config and --set flags
+var Flags Options
+
+// Setting is one configuration key with its effective value
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+	Secret bool
+}
+
+// Display returns the value to show for the setting, hiding secrets
+func (s Setting) Display() string {
+	if s.Secret && s.Value != "" {
+		return redacted
+	}
+	return s.Value
+}
+
+// layer is one source of settings
+type layer struct {
+	source string
+	lookup func(key string) (string, bool)
+}
+
+// reader reads typed settings from a stack of layers. Invalid values are
+// collected rather than returned so every problem is reported at once, and
+// every key read is recorded so the effective configuration can be printed.
+type reader struct {
+	layers   []layer // Highest precedence first
+	settings []Setting
+	problems []string
+}
+
+// newReader stacks the settings file, the environment and the command-line
+// overrides, in increasing order of precedence
+func newReader(opts Options) (*reader, error) {
+	file := opts.File
+	if file == "" {
+		file = DefaultFile
+	}
+
+	fileValues, err := godotenv.Read(file)
+	if errors.Is(err, fs.ErrNotExist) && opts.File == "" {
+		fileValues, err = map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+
+	overrides := make(map[string]string, len(opts.Overrides))
+	for _, override := range opts.Overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid --set %q: expected KEY=VALUE", override)
+		}
+		overrides[strings.TrimSpace(key)] = value
+	}
+
+	return &reader{
+		layers: []layer{
+			{source: SourceFlag, lookup: lookupMap(overrides)},
+			{source: SourceEnv, lookup: os.LookupEnv},
+			{source: SourceFile, lookup: lookupMap(fileValues)},
+		},
+	}, nil
+}
+
+func lookupMap(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+// lookup returns the highest precedence non-empty value for key
+func (r *reader) lookup(key string) (string, string, bool) {
+	for _, l := range r.layers {
+		if value, ok := l.lookup(key); ok && value != "" {
+			return value, l.source, true
+		}
+	}
+	return "", SourceDefault, false
+}
+
+// read looks up key and records it, with def as the value shown when unset
+func (r *reader) read(key, def string, secret bool) (string, bool) {
+	value, source, ok := r.lookup(key)
+	if !ok {
+		value = def
+	}
+	r.settings = append(r.settings, Setting{Key: key, Value: value, Source: source, Secret: secret})
+	return value, ok
+}
+
+// invalid records a problem with a setting
+func (r *reader) invalid(key, format string, args ...any) {
+	r.problems = append(r.problems, fmt.Sprintf("invalid %s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+// String reads a string, using def when unset
+func (r *reader) String(key, def string) string {
+	value, _ := r.read(key, def, false)
+	return value
+}
+
+// Secret reads a string that is redacted when the configuration is printed
+func (r *reader) Secret(key, def string) string {
+	value, _ := r.read(key, def, true)
+	return value
+}
+
+// OneOf reads a string that must be one of allowed, using def when unset
+func (r *reader) OneOf(key, def string, allowed ...string) string {
+	value := r.String(key, def)
+	for _, a := range allowed {
+		if value == a {
+			return value
+		}
+	}
+	r.invalid(key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	return def
+}
+
+// Duration reads a positive duration such as "90s", using def when unset
+func (r *reader) Duration(key string, def time.Duration) time.Duration {
+	value, ok := r.read(key, def.String(), false)
+	if !ok {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		r.invalid(key, "%q is not a duration such as 90s or 15m", value)
+		return def
+	}
+	if d <= 0 {
+		r.invalid(key, "must be positive")
+		return def
+	}
+
+	return d
+}
+
+// Int reads a non-negative integer, using def when unset
+func (r *reader) Int(key string, def int) int {
+	value, ok := r.read(key, strconv.Itoa(def), false)
+	if !ok {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.invalid(key, "%q is not a whole number", value)
+		return def
+	}
+	if n < 0 {
+		r.invalid(key, "cannot be negative")
+		return def
+	}
+
+	return n
+}
+
+// Port reads a TCP port number, using def when unset
+func (r *reader) Port(key string, def int) int {
+	port := r.Int(key, def)
+	if port < 1 || port > 65535 {
+		r.invalid(key, "%d is not a port between 1 and 65535", port)
+		return def
+	}
+	return port
+}
+
+// Bool reads a boolean such as "true" or "0", using def when unset
+func (r *reader) Bool(key string, def bool) bool {
+	value, ok := r.read(key, strconv.FormatBool(def), false)
+	if !ok {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		r.invalid(key, "%q is not true or false", value)
+		return def
+	}
+
+	return b
+}
+
+// Fraction reads a number from 0 to 1, using def when unset
+func (r *reader) Fraction(key string, def float64) float64 {
+	value, ok := r.read(key, strconv.FormatFloat(def, 'g', -1, 64), false)
+	if !ok {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || f > 1 {
+		r.invalid(key, "%q is not a number from 0 to 1", value)
+		return def
+	}
+
+	return f
+}
+
+// List reads a comma-separated list, dropping blank entries, using def when
+// unset
+func (r *reader) List(key string, def []string) []string {
+	value, ok := r.read(key, strings.Join(def, ","), false)
+	if !ok {
+		return def
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
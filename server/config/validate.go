@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// defaultJWTSecret is the placeholder secret from the example settings
+const defaultJWTSecret = "change_this_in_production"
+
+// minJWTSecretLength is the shortest JWT secret accepted
+const minJWTSecretLength = 32
+
+// ValidationError lists every problem found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration, returning a ValidationError listing
+// every unparseable or inconsistent setting
+func (c *Config) Validate() error {
+	problems := append([]string(nil), c.problems...)
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	switch secret := c.Auth.JWTSecret; {
+	case secret == "":
+		problems = append(problems, "JWT_SECRET is required")
+	case secret == defaultJWTSecret:
+		problems = append(problems, "JWT_SECRET is still the example value; set a random secret")
+	case len(secret) < minJWTSecretLength:
+		problems = append(problems, fmt.Sprintf("JWT_SECRET must be at least %d characters", minJWTSecretLength))
+	}
+
+	check(c.Postgres.Host != "", "POSTGRES_HOST is required")
+	check(c.Postgres.User != "", "POSTGRES_USER is required")
+	check(c.Postgres.DB != "", "POSTGRES_DB is required")
+
+	for _, endpoint := range c.Engine.Endpoints() {
+		check(isHTTPURL(endpoint), "invalid engine URL %q: must be an http or https URL", endpoint)
+	}
+	check(isHTTPURL(c.Mail.AppURL), "invalid APP_URL %q: must be an http or https URL", c.Mail.AppURL)
+	check(len(c.HTTP.CORSOrigins) > 0, "HTTP_CORS_ORIGINS must list at least one origin")
+	for _, proxy := range c.HTTP.TrustedProxies {
+		check(isIPOrCIDR(proxy), "invalid HTTP_TRUSTED_PROXIES entry %q: must be an IP address or CIDR", proxy)
+	}
+	check(c.HTTP.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(c.HTTP.MaxBodyBytes > 0, "HTTP_MAX_BODY_BYTES must be positive")
+
+	if c.Matchmaking.Store == "redis" {
+		check(isRedisURL(c.Redis.URL), "invalid REDIS_URL: must be a redis or rediss URL")
+	}
+
+	check(c.Mail.Driver != "smtp" || c.Mail.SMTPHost != "", "SMTP_HOST is required when MAIL_DRIVER is smtp")
+
+	limit := c.LoginLimit
+	check(limit.BackoffBase <= limit.BackoffMax, "LOGIN_BACKOFF_BASE (%s) cannot exceed LOGIN_BACKOFF_MAX (%s)", limit.BackoffBase, limit.BackoffMax)
+	check(limit.AccountLockoutAttempts == 0 || limit.AccountLockoutAttempts > limit.AccountFreeAttempts,
+		"LOGIN_ACCOUNT_LOCKOUT_ATTEMPTS must exceed LOGIN_ACCOUNT_FREE_ATTEMPTS, or be 0 to disable lockouts")
+	check(limit.IPLockoutAttempts == 0 || limit.IPLockoutAttempts > limit.IPFreeAttempts,
+		"LOGIN_IP_LOCKOUT_ATTEMPTS must exceed LOGIN_IP_FREE_ATTEMPTS, or be 0 to disable lockouts")
+
+	for _, rate := range []struct {
+		group  string
+		policy RatePolicy
+	}{
+		{"AUTH", c.RateLimit.Auth},
+		{"MODELS", c.RateLimit.Models},
+		{"MATCHMAKING", c.RateLimit.Matchmaking},
+		{"DEFAULT", c.RateLimit.Default},
+	} {
+		check(rate.policy.PerMinute == 0 || rate.policy.Burst > 0,
+			"RATE_LIMIT_%s_BURST must be positive when RATE_LIMIT_%s_PER_MINUTE is set", rate.group, rate.group)
+	}
+	check(c.Quotas.MaxCodeBytes < c.HTTP.MaxBodyBytes,
+		"MAX_MODEL_CODE_BYTES (%d) must be below HTTP_MAX_BODY_BYTES (%d)", c.Quotas.MaxCodeBytes, c.HTTP.MaxBodyBytes)
+
+	check(c.Adjudication.MaterialThreshold == 0 || c.Adjudication.MaterialPlies > 0,
+		"ADJUDICATION_MATERIAL_PLIES must be positive when ADJUDICATION_MATERIAL_THRESHOLD is set")
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// isIPOrCIDR reports whether s is an IP address or CIDR range
+func isIPOrCIDR(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isRedisURL reports whether s is a redis or rediss URL
+func isRedisURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "redis" || u.Scheme == "rediss") && u.Host != ""
+}
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS games (
+    id SERIAL PRIMARY KEY,
+    match_id VARCHAR(255) NOT NULL,
+    game_id VARCHAR(255) NOT NULL UNIQUE,
+    white_user_id INTEGER REFERENCES users(id),
+    black_user_id INTEGER REFERENCES users(id),
+    white_model_id INTEGER REFERENCES user_models(id),
+    black_model_id INTEGER REFERENCES user_models(id),
+    result VARCHAR(16) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_games_white_user_id ON games(white_user_id);
+CREATE INDEX IF NOT EXISTS idx_games_black_user_id ON games(black_user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS games;
+-- +goose StatementEnd
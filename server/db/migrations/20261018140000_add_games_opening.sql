@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games ADD COLUMN IF NOT EXISTS opening VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE games ADD COLUMN IF NOT EXISTS start_fen VARCHAR(128) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games DROP COLUMN IF EXISTS start_fen;
+ALTER TABLE games DROP COLUMN IF EXISTS opening;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE user_models ADD COLUMN IF NOT EXISTS variants TEXT[] NOT NULL DEFAULT '{standard}';
+
+CREATE TABLE IF NOT EXISTS model_ratings (
+    model_id INTEGER NOT NULL REFERENCES user_models(id) ON DELETE CASCADE,
+    variant VARCHAR(32) NOT NULL,
+    rating INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (model_id, variant)
+);
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS variant VARCHAR(32) NOT NULL DEFAULT 'standard';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games DROP COLUMN IF EXISTS variant;
+DROP TABLE IF EXISTS model_ratings;
+ALTER TABLE user_models DROP COLUMN IF EXISTS variants;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMP WITH TIME ZONE;
+
+-- Deleting a model keeps the games it played, without the model
+ALTER TABLE games DROP CONSTRAINT IF EXISTS games_white_model_id_fkey;
+ALTER TABLE games DROP CONSTRAINT IF EXISTS games_black_model_id_fkey;
+ALTER TABLE games ADD CONSTRAINT games_white_model_id_fkey
+    FOREIGN KEY (white_model_id) REFERENCES user_models(id) ON DELETE SET NULL;
+ALTER TABLE games ADD CONSTRAINT games_black_model_id_fkey
+    FOREIGN KEY (black_model_id) REFERENCES user_models(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games DROP CONSTRAINT IF EXISTS games_white_model_id_fkey;
+ALTER TABLE games DROP CONSTRAINT IF EXISTS games_black_model_id_fkey;
+ALTER TABLE games ADD CONSTRAINT games_white_model_id_fkey
+    FOREIGN KEY (white_model_id) REFERENCES user_models(id);
+ALTER TABLE games ADD CONSTRAINT games_black_model_id_fkey
+    FOREIGN KEY (black_model_id) REFERENCES user_models(id);
+
+ALTER TABLE users DROP COLUMN IF EXISTS banned_at;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
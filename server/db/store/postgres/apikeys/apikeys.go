@@ -0,0 +1,127 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, last_used_at, revoked_at, created_at`
+
+// ErrAPIKeyNotFound is returned when no API key matches a lookup
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// CreateAPIKey stores a new API key
+func (s *Store) CreateAPIKey(ctx context.Context, k *model.APIKey) (*model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + apiKeyColumns
+
+	var created model.APIKey
+	err := s.DB.QueryRowxContext(ctx, query, k.UserID, k.Name, k.Prefix, k.KeyHash, k.Scopes).StructScan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of its value
+func (s *Store) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE key_hash = $1
+	`
+
+	var key model.APIKey
+	err := s.DB.GetContext(ctx, &key, query, hash)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// GetAPIKeysByUserID retrieves every API key a user has created, newest first
+func (s *Store) GetAPIKeysByUserID(ctx context.Context, userID int) ([]*model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	var keys []*model.APIKey
+	err := s.DB.SelectContext(ctx, &keys, query, userID)
+
+	if err != sql.ErrNoRows && err != nil {
+		return nil, fmt.Errorf("failed to get API keys for user: %w", err)
+	}
+
+	if keys == nil {
+		return []*model.APIKey{}, nil
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of a user's API keys
+func (s *Store) RevokeAPIKey(ctx context.Context, id, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE api_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := s.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey records that an API key was just used
+func (s *Store) TouchAPIKey(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	if _, err := s.DB.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to update API key usage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package apikeys
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// StoreInterface defines the contract for API key data access
+type StoreInterface interface {
+	CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	GetAPIKeysByUserID(ctx context.Context, userID int) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id, userID int) error
+	TouchAPIKey(ctx context.Context, id int) error
+}
+
+// Store implements the API key data access
+type Store struct {
+	*sqlx.DB
+	timeout time.Duration // Deadline for each call
+}
+
+// NewStore creates a new API key store instance
+func NewStore(db *sqlx.DB, timeout time.Duration) StoreInterface {
+	return &Store{
+		DB:      db,
+		timeout: timeout,
+	}
+}
@@ -0,0 +1,130 @@
+package games
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// Model IDs read as 0 once the model has been deleted
+const gameColumns = `id, match_id, game_id, white_user_id, black_user_id,
+		COALESCE(white_model_id, 0) AS white_model_id, COALESCE(black_model_id, 0) AS black_model_id, result, reason, adjudication, opening, start_fen, variant, started_at, ended_at`
+
+// CreateGame records a finished game
+func (s *Store) CreateGame(ctx context.Context, g *model.Game) (*model.Game, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO games (match_id, game_id, white_user_id, black_user_id, white_model_id,
+			black_model_id, result, reason, adjudication, opening, start_fen, variant, started_at, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING ` + gameColumns
+
+	var createdGame model.Game
+	err := s.DB.QueryRowxContext(
+		ctx,
+		query,
+		g.MatchID,
+		g.GameID,
+		g.WhiteUserID,
+		g.BlackUserID,
+		g.WhiteModelID,
+		g.BlackModelID,
+		g.Result,
+		g.Reason,
+		g.Adjudication,
+		g.Opening,
+		g.StartFEN,
+		g.Variant,
+		g.StartedAt,
+		g.EndedAt,
+	).StructScan(&createdGame)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create game: %w", err)
+	}
+
+	return &createdGame, nil
+}
+
+// GetGamesByUserID retrieves every game a user played with either color,
+// most recent first
+func (s *Store) GetGamesByUserID(ctx context.Context, userID int) ([]*model.Game, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + gameColumns + `
+		FROM games
+		WHERE white_user_id = $1 OR black_user_id = $1
+		ORDER BY ended_at DESC
+	`
+
+	var games []*model.Game
+	err := s.DB.SelectContext(ctx, &games, query, userID)
+
+	if err != sql.ErrNoRows && err != nil {
+		return nil, fmt.Errorf("failed to get games for user: %w", err)
+	}
+
+	if games == nil {
+		return []*model.Game{}, nil
+	}
+
+	return games, nil
+}
+
+// GetUserStats counts a user's games and their results. Games a user played
+// against themselves count as both a win and a loss.
+func (s *Store) GetUserStats(ctx context.Context, userID int) (*model.UserStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			COUNT(*) AS games,
+			COUNT(*) FILTER (WHERE (white_user_id = $1 AND result = 'white') OR (black_user_id = $1 AND result = 'black')) AS wins,
+			COUNT(*) FILTER (WHERE (white_user_id = $1 AND result = 'black') OR (black_user_id = $1 AND result = 'white')) AS losses,
+			COUNT(*) FILTER (WHERE result = 'draw') AS draws
+		FROM games
+		WHERE white_user_id = $1 OR black_user_id = $1
+	`
+
+	var stats model.UserStats
+	if err := s.DB.GetContext(ctx, &stats, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// GetRecentGameEnd returns when the nth most recent of the games a user
+// finished after since ended, or nil if they finished fewer than n
+func (s *Store) GetRecentGameEnd(ctx context.Context, userID int, since time.Time, n int) (*time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT ended_at
+		FROM games
+		WHERE (white_user_id = $1 OR black_user_id = $1) AND ended_at > $2
+		ORDER BY ended_at DESC
+		OFFSET $3
+		LIMIT 1
+	`
+
+	var endedAt time.Time
+	err := s.DB.GetContext(ctx, &endedAt, query, userID, since, n-1)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent games for user: %w", err)
+	}
+
+	return &endedAt, nil
+}
@@ -0,0 +1,31 @@
+package games
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// StoreInterface defines the contract for game history data access
+type StoreInterface interface {
+	CreateGame(ctx context.Context, game *model.Game) (*model.Game, error)
+	GetGamesByUserID(ctx context.Context, userID int) ([]*model.Game, error)
+	GetUserStats(ctx context.Context, userID int) (*model.UserStats, error)
+	GetRecentGameEnd(ctx context.Context, userID int, since time.Time, n int) (*time.Time, error)
+}
+
+// Store implements the game history data access
+type Store struct {
+	*sqlx.DB
+	timeout time.Duration // Deadline for each call
+}
+
+// NewStore creates a new game store instance
+func NewStore(db *sqlx.DB, timeout time.Duration) StoreInterface {
+	return &Store{
+		DB:      db,
+		timeout: timeout,
+	}
+}
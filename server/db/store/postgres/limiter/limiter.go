@@ -0,0 +1,136 @@
+package limiter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// GetLimiterState retrieves a key's failed attempts. Keys without failures
+// have an empty state.
+func (s *Store) GetLimiterState(ctx context.Context, key string) (*model.LimiterState, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT key, failures, last_failure_at, locked_until
+		FROM limiter_state
+		WHERE key = $1
+	`
+
+	var state model.LimiterState
+	err := s.DB.GetContext(ctx, &state, query, key)
+
+	if err == sql.ErrNoRows {
+		return &model.LimiterState{Key: key}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limiter state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// UpdateLimiterState applies update to a key's state and stores the result.
+// The row is locked while update runs, so concurrent failures of one key
+// are all counted.
+func (s *Store) UpdateLimiterState(ctx context.Context, key string, update func(*model.LimiterState)) (*model.LimiterState, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update limiter state: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO limiter_state (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key); err != nil {
+		return nil, fmt.Errorf("failed to update limiter state: %w", err)
+	}
+
+	var state model.LimiterState
+	err = tx.GetContext(ctx, &state, `
+		SELECT key, failures, last_failure_at, locked_until
+		FROM limiter_state
+		WHERE key = $1
+		FOR UPDATE
+	`, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limiter state: %w", err)
+	}
+
+	update(&state)
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE limiter_state
+		SET failures = $2, last_failure_at = $3, locked_until = $4
+		WHERE key = $1
+	`, key, state.Failures, state.LastFailureAt, state.LockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update limiter state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update limiter state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// DeleteLimiterState forgets a key's failed attempts
+func (s *Store) DeleteLimiterState(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM limiter_state WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to delete limiter state: %w", err)
+	}
+
+	return nil
+}
+
+// RecordLockout stores the audit record of a lockout
+func (s *Store) RecordLockout(ctx context.Context, lockout *model.Lockout) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO lockouts (limiter, key, failures, locked_until)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := s.DB.ExecContext(ctx, query, lockout.Limiter, lockout.Key, lockout.Failures, lockout.LockedUntil); err != nil {
+		return fmt.Errorf("failed to record lockout: %w", err)
+	}
+
+	return nil
+}
+
+// ListLockouts retrieves the most recent lockouts, newest first
+func (s *Store) ListLockouts(ctx context.Context, limit int) ([]*model.Lockout, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, limiter, key, failures, locked_until, created_at
+		FROM lockouts
+		ORDER BY created_at DESC
+		LIMIT $1
+	`
+
+	var lockouts []*model.Lockout
+	err := s.DB.SelectContext(ctx, &lockouts, query, limit)
+
+	if err != sql.ErrNoRows && err != nil {
+		return nil, fmt.Errorf("failed to list lockouts: %w", err)
+	}
+
+	if lockouts == nil {
+		return []*model.Lockout{}, nil
+	}
+
+	return lockouts, nil
+}
@@ -0,0 +1,32 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// StoreInterface defines the contract for limiter data access
+type StoreInterface interface {
+	GetLimiterState(ctx context.Context, key string) (*model.LimiterState, error)
+	UpdateLimiterState(ctx context.Context, key string, update func(*model.LimiterState)) (*model.LimiterState, error)
+	DeleteLimiterState(ctx context.Context, key string) error
+	RecordLockout(ctx context.Context, lockout *model.Lockout) error
+	ListLockouts(ctx context.Context, limit int) ([]*model.Lockout, error)
+}
+
+// Store implements the limiter data access
+type Store struct {
+	*sqlx.DB
+	timeout time.Duration // Deadline for each call
+}
+
+// NewStore creates a new limiter store instance
+func NewStore(db *sqlx.DB, timeout time.Duration) StoreInterface {
+	return &Store{
+		DB:      db,
+		timeout: timeout,
+	}
+}
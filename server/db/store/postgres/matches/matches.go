@@ -0,0 +1,58 @@
+package matches
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// SaveActiveMatch inserts an active match or replaces its saved state
+func (s *Store) SaveActiveMatch(ctx context.Context, match *model.ActiveMatch) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO active_matches (id, game_id, data, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE
+		SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := s.DB.ExecContext(ctx, query, match.ID, match.GameID, match.Data); err != nil {
+		return fmt.Errorf("failed to save active match: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteActiveMatch removes a match that has ended
+func (s *Store) DeleteActiveMatch(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM active_matches WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete active match: %w", err)
+	}
+
+	return nil
+}
+
+// ListActiveMatches retrieves every saved match, ordered by ID
+func (s *Store) ListActiveMatches(ctx context.Context) ([]*model.ActiveMatch, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, game_id, data, updated_at
+		FROM active_matches
+		ORDER BY id
+	`
+
+	matches := []*model.ActiveMatch{}
+	if err := s.DB.SelectContext(ctx, &matches, query); err != nil {
+		return nil, fmt.Errorf("failed to list active matches: %w", err)
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,30 @@
+package matches
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// StoreInterface defines the contract for active match data access
+type StoreInterface interface {
+	SaveActiveMatch(ctx context.Context, match *model.ActiveMatch) error
+	DeleteActiveMatch(ctx context.Context, id string) error
+	ListActiveMatches(ctx context.Context) ([]*model.ActiveMatch, error)
+}
+
+// Store implements the active match data access
+type Store struct {
+	*sqlx.DB
+	timeout time.Duration // Deadline for each call
+}
+
+// NewStore creates a new active match store instance
+func NewStore(db *sqlx.DB, timeout time.Duration) StoreInterface {
+	return &Store{
+		DB:      db,
+		timeout: timeout,
+	}
+}
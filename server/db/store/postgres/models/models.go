@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -8,40 +9,79 @@ import (
 	"github.com/ajlaz/checkmAIt/server/model"
 )
 
-// CreateModel inserts a new model into the database
-func (s *Store) CreateModel(m *model.UserModel) (*model.UserModel, error) {
+// ErrModelLimitReached is returned when a user already owns the most models
+// they may create
+var ErrModelLimitReached = errors.New("model limit reached")
+
+// CreateModel inserts a new model into the database. If maxModels is
+// positive, the user's row is locked while their models are counted, so
+// concurrent creates can't take them past the limit.
+func (s *Store) CreateModel(ctx context.Context, m *model.UserModel, maxModels int) (*model.UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model: %w", err)
+	}
+	defer tx.Rollback()
+
+	if maxModels > 0 {
+		if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, m.UserID); err != nil {
+			return nil, fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var count int
+		if err := tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM user_models WHERE user_id = $1`, m.UserID); err != nil {
+			return nil, fmt.Errorf("failed to count models for user: %w", err)
+		}
+		if count >= maxModels {
+			return nil, ErrModelLimitReached
+		}
+	}
+
 	query := `
-		INSERT INTO user_models (user_id, name, model, rating)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, name, model, rating
+		INSERT INTO user_models (user_id, name, model, rating, variants, public)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, user_id, name, model, rating, variants, public
 	`
 
 	var createdModel model.UserModel
-	err := s.DB.QueryRowx(
+	err = tx.QueryRowxContext(
+		ctx,
 		query,
 		m.UserID,
 		m.Name,
 		m.Model,
 		m.Rating,
+		m.Variants,
+		m.Public,
 	).StructScan(&createdModel)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create model: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create model: %w", err)
+	}
+
 	return &createdModel, nil
 }
 
 // GetModelByID retrieves a model by its ID
-func (s *Store) GetModelByID(id int) (*model.UserModel, error) {
+func (s *Store) GetModelByID(ctx context.Context, id int) (*model.UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
 	query := `
-		SELECT id, user_id, name, model, rating 
+		SELECT id, user_id, name, model, rating, variants, public
 		FROM user_models 
 		WHERE id = $1
 	`
 
 	var userModel model.UserModel
-	err := s.DB.Get(&userModel, query, id)
+	err := s.DB.GetContext(ctx, &userModel, query, id)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("model not found")
@@ -55,15 +95,18 @@ func (s *Store) GetModelByID(id int) (*model.UserModel, error) {
 }
 
 // GetModelsByUserID retrieves all models for a specific user
-func (s *Store) GetModelsByUserID(userID int) ([]*model.UserModel, error) {
+func (s *Store) GetModelsByUserID(ctx context.Context, userID int) ([]*model.UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
 	query := `
-		SELECT id, user_id, name, model, rating 
+		SELECT id, user_id, name, model, rating, variants, public
 		FROM user_models 
 		WHERE user_id = $1
 	`
 
 	var models []*model.UserModel
-	err := s.DB.Select(&models, query, userID)
+	err := s.DB.SelectContext(ctx, &models, query, userID)
 
 	if err != sql.ErrNoRows && err != nil {
 		// Return error only for actual DB errors, not for "no rows" cases
@@ -79,21 +122,27 @@ func (s *Store) GetModelsByUserID(userID int) ([]*model.UserModel, error) {
 }
 
 // UpdateModel updates an existing model
-func (s *Store) UpdateModel(m *model.UserModel) (*model.UserModel, error) {
+func (s *Store) UpdateModel(ctx context.Context, m *model.UserModel) (*model.UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
 	query := `
 		UPDATE user_models 
-		SET name = $2, model = $3, rating = $4
+		SET name = $2, model = $3, rating = $4, variants = $5, public = $6
 		WHERE id = $1
-		RETURNING id, user_id, name, model, rating
+		RETURNING id, user_id, name, model, rating, variants, public
 	`
 
 	var updatedModel model.UserModel
-	err := s.DB.QueryRowx(
+	err := s.DB.QueryRowxContext(
+		ctx,
 		query,
 		m.ID,
 		m.Name,
 		m.Model,
 		m.Rating,
+		m.Variants,
+		m.Public,
 	).StructScan(&updatedModel)
 
 	if err == sql.ErrNoRows {
@@ -108,10 +157,13 @@ func (s *Store) UpdateModel(m *model.UserModel) (*model.UserModel, error) {
 }
 
 // DeleteModel deletes a model by ID
-func (s *Store) DeleteModel(id int) error {
+func (s *Store) DeleteModel(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
 	query := `DELETE FROM user_models WHERE id = $1`
 
-	result, err := s.DB.Exec(query, id)
+	result, err := s.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete model: %w", err)
 	}
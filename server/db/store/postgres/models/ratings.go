@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// GetVariantRatings retrieves a model's ratings in every variant it has
+// played other than standard chess
+func (s *Store) GetVariantRatings(ctx context.Context, modelID int) ([]*model.VariantRating, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT model_id, variant, rating
+		FROM model_ratings
+		WHERE model_id = $1
+		ORDER BY variant
+	`
+
+	var ratings []*model.VariantRating
+	err := s.DB.SelectContext(ctx, &ratings, query, modelID)
+
+	if err != sql.ErrNoRows && err != nil {
+		return nil, fmt.Errorf("failed to get variant ratings: %w", err)
+	}
+
+	if ratings == nil {
+		return []*model.VariantRating{}, nil
+	}
+
+	return ratings, nil
+}
+
+// GetVariantRating retrieves a model's rating in a variant, or the default
+// rating if it hasn't played the variant yet
+func (s *Store) GetVariantRating(ctx context.Context, modelID int, variant string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT rating
+		FROM model_ratings
+		WHERE model_id = $1 AND variant = $2
+	`
+
+	var rating int
+	err := s.DB.GetContext(ctx, &rating, query, modelID, variant)
+
+	if err == sql.ErrNoRows {
+		return model.DefaultRating, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get variant rating: %w", err)
+	}
+
+	return rating, nil
+}
+
+// SetVariantRating stores a model's rating in a variant
+func (s *Store) SetVariantRating(ctx context.Context, modelID int, variant string, rating int) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO model_ratings (model_id, variant, rating)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (model_id, variant) DO UPDATE SET rating = EXCLUDED.rating, updated_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := s.DB.ExecContext(ctx, query, modelID, variant, rating); err != nil {
+		return fmt.Errorf("failed to set variant rating: %w", err)
+	}
+
+	return nil
+}
+
+// ResetRatings puts a model back at the default rating in every variant
+func (s *Store) ResetRatings(ctx context.Context, modelID int) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to reset ratings: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE user_models SET rating = $2 WHERE id = $1`, modelID, model.DefaultRating)
+	if err != nil {
+		return fmt.Errorf("failed to reset rating: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("model not found")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM model_ratings WHERE model_id = $1`, modelID); err != nil {
+		return fmt.Errorf("failed to reset variant ratings: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -1,27 +1,36 @@
 package models
 
 import (
+	"context"
+	"time"
+
 	"github.com/ajlaz/checkmAIt/server/model"
 	"github.com/jmoiron/sqlx"
 )
 
 // StoreInterface defines the contract for user model data access
 type StoreInterface interface {
-	CreateModel(model *model.UserModel) (*model.UserModel, error)
-	GetModelByID(id int) (*model.UserModel, error)
-	GetModelsByUserID(userID int) ([]*model.UserModel, error)
-	UpdateModel(model *model.UserModel) (*model.UserModel, error)
-	DeleteModel(id int) error
+	CreateModel(ctx context.Context, model *model.UserModel, maxModels int) (*model.UserModel, error)
+	GetModelByID(ctx context.Context, id int) (*model.UserModel, error)
+	GetModelsByUserID(ctx context.Context, userID int) ([]*model.UserModel, error)
+	UpdateModel(ctx context.Context, model *model.UserModel) (*model.UserModel, error)
+	DeleteModel(ctx context.Context, id int) error
+	GetVariantRatings(ctx context.Context, modelID int) ([]*model.VariantRating, error)
+	GetVariantRating(ctx context.Context, modelID int, variant string) (int, error)
+	SetVariantRating(ctx context.Context, modelID int, variant string, rating int) error
+	ResetRatings(ctx context.Context, modelID int) error
 }
 
 // Store implements the user model data access
 type Store struct {
 	*sqlx.DB
+	timeout time.Duration // Deadline for each call
 }
 
 // NewStore creates a new model store instance
-func NewStore(db *sqlx.DB) StoreInterface {
+func NewStore(db *sqlx.DB, timeout time.Duration) StoreInterface {
 	return &Store{
-		DB: db,
+		DB:      db,
+		timeout: timeout,
 	}
 }
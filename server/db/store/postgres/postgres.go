@@ -2,23 +2,49 @@ package postgres
 
 import (
 	"log"
+	"net"
+	"net/url"
+	"strconv"
 
+	"github.com/XSAM/otelsql"
 	"github.com/ajlaz/checkmAIt/server/config"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 const driver = "postgres"
 
+// Connect opens the database. Every query made through it is traced; row
+// iteration and connection housekeeping are left out to keep traces small.
 func Connect(cfg *config.Config) *sqlx.DB {
-	db, err := sqlx.Connect(driver, dsn(cfg))
+	sqlDB, err := otelsql.Open(driver, dsn(cfg),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			DisableErrSkip:       true,
+		}),
+	)
 	if err != nil {
 		log.Fatalf("failed to connect to Postgres: %v", err)
 	}
+
+	db := sqlx.NewDb(sqlDB, driver)
+	if err := db.Ping(); err != nil {
+		log.Fatalf("failed to connect to Postgres: %v", err)
+	}
 	return db
 }
 
 func dsn(cfg *config.Config) string {
 	// Use the host from the config instead of hardcoding localhost
-	return "postgres://" + cfg.Postgres.User + ":" + cfg.Postgres.Password + "@" + cfg.Postgres.Host + ":" + cfg.Postgres.Port + "/" + cfg.Postgres.DB + "?sslmode=disable"
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Postgres.User, cfg.Postgres.Password),
+		Host:     net.JoinHostPort(cfg.Postgres.Host, strconv.Itoa(cfg.Postgres.Port)),
+		Path:     "/" + cfg.Postgres.DB,
+		RawQuery: "sslmode=disable",
+	}
+	return u.String()
 }
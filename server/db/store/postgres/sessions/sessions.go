@@ -0,0 +1,166 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// ErrSessionNotFound is returned when no session has the requested ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// CreateSession records a new login session
+func (s *Store) CreateSession(ctx context.Context, session *model.Session) (*model.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO sessions (id, user_id)
+		VALUES ($1, $2)
+		RETURNING id, user_id, revoked_at, created_at
+	`
+
+	var created model.Session
+	err := s.DB.QueryRowxContext(ctx, query, session.ID, session.UserID).StructScan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetSessionByID retrieves a session by its ID
+func (s *Store) GetSessionByID(ctx context.Context, id string) (*model.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, revoked_at, created_at
+		FROM sessions
+		WHERE id = $1
+	`
+
+	var session model.Session
+	err := s.DB.GetContext(ctx, &session, query, id)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// RevokeSession marks a session as revoked. Revoking an already revoked
+// session keeps the original revocation time.
+func (s *Store) RevokeSession(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := s.DB.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user other than
+// exceptID, which may be empty to revoke them all
+func (s *Store) RevokeUserSessions(ctx context.Context, userID int, exceptID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+
+	if _, err := s.DB.ExecContext(ctx, query, userID, exceptID); err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	return nil
+}
+
+// CreateRefreshToken stores a refresh token's hash
+func (s *Store) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) (*model.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, session_id, token_hash, expires_at, used_at, created_at
+	`
+
+	var created model.RefreshToken
+	err := s.DB.QueryRowxContext(ctx, query, token.SessionID, token.TokenHash, token.ExpiresAt).StructScan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by the hash of its value
+func (s *Store) GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		SELECT id, session_id, token_hash, expires_at, used_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var token model.RefreshToken
+	err := s.DB.GetContext(ctx, &token, query, hash)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("refresh token not found")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed marks a refresh token as exchanged, reporting false if
+// it had already been used
+func (s *Store) MarkRefreshTokenUsed(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	result, err := s.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,34 @@
+package sessions
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// StoreInterface defines the contract for login session data access
+type StoreInterface interface {
+	CreateSession(ctx context.Context, session *model.Session) (*model.Session, error)
+	GetSessionByID(ctx context.Context, id string) (*model.Session, error)
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID int, exceptID string) error
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) (*model.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id int) (bool, error)
+}
+
+// Store implements the login session data access
+type Store struct {
+	*sqlx.DB
+	timeout time.Duration // Deadline for each call
+}
+
+// NewStore creates a new session store instance
+func NewStore(db *sqlx.DB, timeout time.Duration) StoreInterface {
+	return &Store{
+		DB:      db,
+		timeout: timeout,
+	}
+}
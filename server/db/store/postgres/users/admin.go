@@ -0,0 +1,75 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// ListUsers retrieves every user ordered by ID
+func (s *Store) ListUsers(ctx context.Context) ([]*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var users []*model.User
+	err := s.DB.SelectContext(ctx, &users, "SELECT "+userColumns+" FROM users ORDER BY id")
+
+	if err != sql.ErrNoRows && err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	if users == nil {
+		return []*model.User{}, nil
+	}
+
+	return users, nil
+}
+
+// SetUserRole changes a user's role
+func (s *Store) SetUserRole(ctx context.Context, id int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, "UPDATE users SET role = $2 WHERE id = $1", id, role)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	return requireRow(result)
+}
+
+// SetUserBanned bans or unbans a user. Banning an already banned user keeps
+// the original ban time.
+func (s *Store) SetUserBanned(ctx context.Context, id int, banned bool) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `UPDATE users SET banned_at = NULL WHERE id = $1`
+	if banned {
+		query = `UPDATE users SET banned_at = COALESCE(banned_at, CURRENT_TIMESTAMP) WHERE id = $1`
+	}
+
+	result, err := s.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user ban: %w", err)
+	}
+
+	return requireRow(result)
+}
+
+// requireRow returns an error if an update matched no user
+func requireRow(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package users
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -8,11 +9,15 @@ import (
 )
 
 // GetUserByEmail retrieves a user by email
-func (s *Store) GetUserByEmail(email string) (*model.User, error) {
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
 	var user model.User
-	err := s.DB.Get(
+	err := s.DB.GetContext(
+		ctx,
 		&user,
-		"SELECT id, username, email, password_hash as password FROM users WHERE email=$1",
+		"SELECT "+userColumns+" FROM users WHERE email=$1",
 		email,
 	)
 
@@ -28,15 +33,18 @@ func (s *Store) GetUserByEmail(email string) (*model.User, error) {
 }
 
 // CreateUser creates a new user
-func (s *Store) CreateUser(user *model.User) (*model.User, error) {
+func (s *Store) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
 	query := `
 		INSERT INTO users (username, email, password_hash) 
 		VALUES ($1, $2, $3) 
-		RETURNING id, username, email, password_hash as password
-	`
+		RETURNING ` + userColumns
 
 	var createdUser model.User
-	err := s.DB.QueryRowx(
+	err := s.DB.QueryRowxContext(
+		ctx,
 		query,
 		user.Username,
 		user.Email,
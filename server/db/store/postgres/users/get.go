@@ -1,17 +1,22 @@
 package users
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
 	"github.com/ajlaz/checkmAIt/server/model"
 )
 
-func (s *Store) GetUserByID(id string) (*model.User, error) {
+func (s *Store) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
 	var user model.User
-	err := s.DB.Get(
+	err := s.DB.GetContext(
+		ctx,
 		&user,
-		"SELECT id, username, email, password_hash as password FROM users WHERE id=$1",
+		"SELECT "+userColumns+" FROM users WHERE id=$1",
 		id,
 	)
 
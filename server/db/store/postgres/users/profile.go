@@ -0,0 +1,95 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/lib/pq"
+)
+
+// ErrUserExists is returned when a username or email is already taken
+var ErrUserExists = errors.New("username or email already in use")
+
+// uniqueViolation is the PostgreSQL error code for a unique constraint
+// violation
+const uniqueViolation = "23505"
+
+// UpdateUser saves a user's username, email and email verification time
+func (s *Store) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET username = $2, email = $3, email_verified_at = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + userColumns
+
+	var updated model.User
+	err := s.DB.QueryRowxContext(ctx, query, user.ID, user.Username, user.Email, user.EmailVerifiedAt).StructScan(&updated)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("user not found")
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return nil, ErrUserExists
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// AnonymizeUser deletes an account while keeping the games it played. The
+// user row stays, stripped of personal data and unable to log in, so games
+// still reference it; the user's models, API keys, tokens and sessions are
+// removed.
+func (s *Store) AnonymizeUser(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET username = $2::text || id,
+			email = $2::text || id || '@deleted.invalid',
+			password_hash = '',
+			role = 'user',
+			email_verified_at = NULL,
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id, model.DeletedUsernamePrefix)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	if err := requireRow(result); err != nil {
+		return err
+	}
+
+	cleanup := []string{
+		`DELETE FROM user_models WHERE user_id = $1`,
+		`DELETE FROM api_keys WHERE user_id = $1`,
+		`DELETE FROM user_tokens WHERE user_id = $1`,
+		`DELETE FROM sessions WHERE user_id = $1`,
+	}
+	for _, query := range cleanup {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete user data: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -1,22 +1,39 @@
 package users
 
 import (
+	"context"
+	"time"
+
 	"github.com/ajlaz/checkmAIt/server/model"
 	"github.com/jmoiron/sqlx"
 )
 
+const userColumns = `id, username, email, password_hash as password, role, banned_at, email_verified_at, deleted_at, created_at`
+
 type StoreInterface interface {
-	GetUserByID(id string) (*model.User, error)
-	GetUserByEmail(email string) (*model.User, error)
-	CreateUser(user *model.User) (*model.User, error)
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
+	ListUsers(ctx context.Context) ([]*model.User, error)
+	SetUserRole(ctx context.Context, id int, role string) error
+	SetUserBanned(ctx context.Context, id int, banned bool) error
+	SetEmailVerified(ctx context.Context, id int) error
+	SetPasswordHash(ctx context.Context, id int, hash string) error
+	CreateUserToken(ctx context.Context, token *model.UserToken) (*model.UserToken, error)
+	ConsumeUserToken(ctx context.Context, hash, purpose string) (*model.UserToken, error)
+	InvalidateUserTokens(ctx context.Context, userID int, purpose string) error
+	UpdateUser(ctx context.Context, user *model.User) (*model.User, error)
+	AnonymizeUser(ctx context.Context, id int) error
 }
 
 type Store struct {
 	*sqlx.DB
+	timeout time.Duration // Deadline for each call
 }
 
-func NewStore(db *sqlx.DB) StoreInterface {
+func NewStore(db *sqlx.DB, timeout time.Duration) StoreInterface {
 	return &Store{
-		DB: db,
+		DB:      db,
+		timeout: timeout,
 	}
 }
@@ -0,0 +1,112 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+const userTokenColumns = `id, user_id, purpose, token_hash, expires_at, used_at, created_at`
+
+// ErrUserTokenNotFound is returned when a token is unknown, already used or
+// expired
+var ErrUserTokenNotFound = errors.New("user token not found")
+
+// SetEmailVerified marks a user's email address as verified. Verifying an
+// already verified address keeps the original time.
+func (s *Store) SetEmailVerified(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(
+		ctx,
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP) WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return requireRow(result)
+}
+
+// SetPasswordHash replaces a user's password hash
+func (s *Store) SetPasswordHash(ctx context.Context, id int, hash string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, "UPDATE users SET password_hash = $2 WHERE id = $1", id, hash)
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+
+	return requireRow(result)
+}
+
+// CreateUserToken stores a user token's hash
+func (s *Store) CreateUserToken(ctx context.Context, token *model.UserToken) (*model.UserToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + userTokenColumns
+
+	var created model.UserToken
+	err := s.DB.QueryRowxContext(ctx, query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).StructScan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user token: %w", err)
+	}
+
+	return &created, nil
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns it.
+// The check and update are one statement, so a token can only be consumed
+// once even under concurrent requests.
+func (s *Store) ConsumeUserToken(ctx context.Context, hash, purpose string) (*model.UserToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING ` + userTokenColumns
+
+	var token model.UserToken
+	err := s.DB.QueryRowxContext(ctx, query, hash, purpose).StructScan(&token)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserTokenNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume user token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// InvalidateUserTokens marks every outstanding token of a user for purpose
+// as used
+func (s *Store) InvalidateUserTokens(ctx context.Context, userID int, purpose string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	if _, err := s.DB.ExecContext(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"context"
+	"log"
+
+	"github.com/ajlaz/checkmAIt/server/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Connect opens a client for the configured Redis server and checks that it
+// answers
+func Connect(cfg *config.Config) *redis.Client {
+	opts, err := redis.ParseURL(cfg.Redis.URL)
+	if err != nil {
+		log.Fatalf("invalid Redis URL: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		log.Fatalf("failed to connect to Redis: %v", err)
+	}
+	return client
+}
@@ -2,10 +2,38 @@ package logging
 
 import (
 	"context"
+	"io"
+	"os"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
+// Config controls the base logger
+type Config struct {
+	Level  string // zerolog level name such as "debug" or "info"
+	Format string // "json" or "console"
+}
+
+// New creates the base logger and makes it the fallback for contexts
+// without a logger attached
+func New(cfg Config) (zerolog.Logger, error) {
+	level, err := zerolog.ParseLevel(cfg.Level)
+	if err != nil {
+		return zerolog.Logger{}, err
+	}
+
+	var w io.Writer = os.Stdout
+	if cfg.Format == "console" {
+		w = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	}
+
+	logger := zerolog.New(w).Level(level).With().Timestamp().Logger()
+	zerolog.DefaultContextLogger = &logger
+
+	return logger, nil
+}
+
 // Returns a new context with the logger attached
 func WithContext(log zerolog.Logger, ctx context.Context) context.Context {
 	return log.WithContext(ctx)
@@ -15,3 +43,17 @@ func WithContext(log zerolog.Logger, ctx context.Context) context.Context {
 func FromContext(ctx context.Context) zerolog.Logger {
 	return zerolog.Ctx(ctx).With().Logger()
 }
+
+type requestIDKey struct{}
+
+// WithRequestID returns a new context carrying the ID of the request being
+// served
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "" if none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
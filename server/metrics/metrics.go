@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "checkmait"
+
+// registry holds every metric served on /metrics
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration observes every HTTP request by route and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// MatchCreationDuration observes how long it takes to create a match
+	// once two players are paired, including creating its game on an engine
+	MatchCreationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "match_creation_duration_seconds",
+		Help:      "Time taken to create a match for two paired players.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// EngineCreateGameErrors counts failed attempts to create a game on an
+	// engine instance
+	EngineCreateGameErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "engine_create_game_errors_total",
+		Help:      "Failed attempts to create a game, by engine instance.",
+	}, []string{"engine"})
+
+	// RatingUpdates counts rating updates after recorded games
+	RatingUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rating_updates_total",
+		Help:      "Rating updates after recorded games, by variant and outcome.",
+	}, []string{"variant", "outcome"})
+
+	// Logins counts successful logins
+	Logins = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Successful logins.",
+	})
+
+	// LoginFailures counts rejected logins
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Rejected logins, by reason.",
+	}, []string{"reason"})
+
+	// RateLimitedRequests counts requests refused for exceeding a rate limit
+	RateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests refused for exceeding a rate limit, by route group.",
+	}, []string{"group"})
+)
+
+// Login failure reasons
+const (
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginBanned             = "banned"
+	LoginRateLimited        = "rate_limited"
+)
+
+// Rating update outcomes
+const (
+	OutcomeDecisive = "decisive"
+	OutcomeDraw     = "draw"
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		MatchCreationDuration,
+		EngineCreateGameErrors,
+		RatingUpdates,
+		Logins,
+		LoginFailures,
+		RateLimitedRequests,
+	)
+}
+
+// RegisterMatchmaking adds gauges read from stats, which returns the number
+// of queued players and active matches, each time metrics are scraped
+func RegisterMatchmaking(stats func(context.Context) (int, int)) {
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "matchmaking_queued_players",
+			Help:      "Players waiting in every matchmaking queue.",
+		}, func() float64 {
+			queued, _ := stats(context.Background())
+			return float64(queued)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "matchmaking_active_matches",
+			Help:      "Matches that have not yet finished.",
+		}, func() float64 {
+			_, active := stats(context.Background())
+			return float64(active)
+		}),
+	)
+}
+
+// RegisterDB adds the connection pool statistics of db
+func RegisterDB(db *sql.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// ActiveMatch is a match that is still being played, saved so matchmaking
+// can restore it after a restart. Data holds the match as JSON.
+type ActiveMatch struct {
+	ID        string    `json:"id" db:"id"`
+	GameID    string    `json:"game_id" db:"game_id"`
+	Data      []byte    `json:"data" db:"data"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyPrefix starts every API key so keys are recognizable in configs and
+// can be told apart from JWTs in the Authorization header
+const APIKeyPrefix = "cmk_"
+
+// API key scopes
+const (
+	ScopeModelsRead  = "models:read"
+	ScopeModelsWrite = "models:write"
+	ScopeMatchesRun  = "matches:run"
+)
+
+// Scopes lists every scope an API key can be granted
+var Scopes = []string{ScopeModelsRead, ScopeModelsWrite, ScopeMatchesRun}
+
+// ValidScope reports whether scope can be granted to an API key
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is a personal access key for scripts and CI. Only a hash of the key
+// is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         int            `json:"id" db:"id"`
+	UserID     int            `json:"user_id" db:"user_id"`
+	Name       string         `json:"name" db:"name"`
+	Prefix     string         `json:"prefix" db:"prefix"`
+	KeyHash    string         `json:"-" db:"key_hash"`
+	Scopes     pq.StringArray `json:"scopes" db:"scopes"`
+	LastUsedAt *time.Time     `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time     `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Game results
+const (
+	ResultWhite = "white"
+	ResultBlack = "black"
+	ResultDraw  = "draw"
+)
+
+// Game is the recorded outcome of a finished match
+type Game struct {
+	ID           int       `json:"id" db:"id"`
+	MatchID      string    `json:"match_id" db:"match_id"`
+	GameID       string    `json:"game_id" db:"game_id"`
+	WhiteUserID  int       `json:"white_user_id" db:"white_user_id"`
+	BlackUserID  int       `json:"black_user_id" db:"black_user_id"`
+	WhiteModelID int       `json:"white_model_id" db:"white_model_id"`
+	BlackModelID int       `json:"black_model_id" db:"black_model_id"`
+	Result       string    `json:"result" db:"result"` // "white", "black" or "draw"
+	Reason       string    `json:"reason" db:"reason"`
+	Adjudication string    `json:"adjudication" db:"adjudication"` // Rule that decided an adjudicated game, if any
+	Opening      string    `json:"opening" db:"opening"`           // Name of the opening the game started from, if any
+	StartFEN     string    `json:"start_fen" db:"start_fen"`       // Starting position; empty for the initial position
+	Variant      string    `json:"variant" db:"variant"`
+	StartedAt    time.Time `json:"started_at" db:"started_at"`
+	EndedAt      time.Time `json:"ended_at" db:"ended_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// LimiterState is the failed-attempt history of one limiter key, such as an
+// account or client IP
+type LimiterState struct {
+	Key           string     `json:"key" db:"key"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt *time.Time `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until" db:"locked_until"`
+}
+
+// Lockout is the audit record of a key being locked out after too many
+// failed attempts
+type Lockout struct {
+	ID          int       `json:"id" db:"id"`
+	Limiter     string    `json:"limiter" db:"limiter"`
+	Key         string    `json:"key" db:"key"`
+	Failures    int       `json:"failures" db:"failures"`
+	LockedUntil time.Time `json:"locked_until" db:"locked_until"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,33 @@
+package model
+
+// User roles, from least to most privileged
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Roles lists every role from least to most privileged
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// ValidRole reports whether role exists
+func ValidRole(role string) bool {
+	return roleRank(role) >= 0
+}
+
+// RoleAtLeast reports whether role grants everything required does. Unknown
+// roles grant nothing.
+func RoleAtLeast(role, required string) bool {
+	rank := roleRank(role)
+	return rank >= 0 && rank >= roleRank(required)
+}
+
+// roleRank returns a role's position in Roles, or -1 if it doesn't exist
+func roleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Session is a login session. Every refresh token issued for a login belongs
+// to its session, so revoking the session invalidates the whole token family.
+type Session struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RefreshToken is a single-use token that can be exchanged for a new access
+// token. Only a hash of the token is stored.
+type RefreshToken struct {
+	ID        int        `json:"id" db:"id"`
+	SessionID string     `json:"session_id" db:"session_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"` // Set once the token has been exchanged
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
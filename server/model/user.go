@@ -1,8 +1,53 @@
 package model
 
+import (
+	"strings"
+	"time"
+)
+
+// DeletedUsernamePrefix starts the username of every deleted account. It is
+// reserved, so no signup or rename can take an anonymized account's name.
+const DeletedUsernamePrefix = "deleted-user-"
+
 type User struct {
-	ID       int64  `json:"id" db:"id"`
-	Username string `json:"username" db:"username"`
-	Email    string `json:"email" db:"email"`
-	Password string `json:"-" db:"password"`
+	ID       int64      `json:"id" db:"id"`
+	Username string     `json:"username" db:"username"`
+	Email    string     `json:"email" db:"email"`
+	Password string     `json:"-" db:"password"`
+	Role     string     `json:"role" db:"role"`
+	BannedAt *time.Time `json:"banned_at,omitempty" db:"banned_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	DeletedAt       *time.Time `json:"-" db:"deleted_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// EmailVerified reports whether the user has confirmed their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Deleted reports whether the user deleted their account. Deleted accounts
+// are kept anonymized so their games stay intact.
+func (u *User) Deleted() bool {
+	return u.DeletedAt != nil
+}
+
+// ReservedUsername reports whether username is kept for the server's own
+// use and can't be chosen by a user
+func ReservedUsername(username string) bool {
+	return strings.HasPrefix(strings.ToLower(username), DeletedUsernamePrefix)
+}
+
+// Banned reports whether the user has been banned
+func (u *User) Banned() bool {
+	return u.BannedAt != nil
+}
+
+// UserStats aggregates a user's recorded games
+type UserStats struct {
+	Games  int `json:"games" db:"games"`
+	Wins   int `json:"wins" db:"wins"`
+	Losses int `json:"losses" db:"losses"`
+	Draws  int `json:"draws" db:"draws"`
 }
@@ -1,19 +1,49 @@
 package model
 
+import "github.com/lib/pq"
+
+// DefaultRating is the rating a model starts with in every variant
+const DefaultRating = 400
+
 type UserModel struct {
-	ID     int    `json:"id" db:"id"`
-	UserID int    `json:"user_id" db:"user_id"`
-	Name   string `json:"name" db:"name"`
-	Model  string `json:"model" db:"model"`
-	Rating int    `json:"rating" db:"rating"`
+	ID       int            `json:"id" db:"id"`
+	UserID   int            `json:"user_id" db:"user_id"`
+	Name     string         `json:"name" db:"name"`
+	Model    string         `json:"model,omitempty" db:"model"` // Code, only shown to the owner
+	Rating   int            `json:"rating" db:"rating"`         // Standard chess rating
+	Variants pq.StringArray `json:"variants" db:"variants"`     // Variants the model can play
+	Public   bool           `json:"public" db:"public"`         // Whether the model is listed on its owner's profile
 }
 
 // NewUserModel creates a new UserModel with default values
-func NewUserModel(userID int, name, modelCode string) *UserModel {
+func NewUserModel(userID int, name, modelCode string, variants []string, public bool) *UserModel {
+	if len(variants) == 0 {
+		variants = []string{VariantStandard}
+	}
+
 	return &UserModel{
-		UserID: userID,
-		Name:   name,
-		Model:  modelCode,
-		Rating: 400, // Default rating
+		UserID:   userID,
+		Name:     name,
+		Model:    modelCode,
+		Rating:   DefaultRating,
+		Variants: variants,
+		Public:   public,
 	}
 }
+
+// SupportsVariant reports whether the model declared it can play variant
+func (m *UserModel) SupportsVariant(variant string) bool {
+	for _, v := range m.Variants {
+		if v == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// VariantRating is a model's rating in a variant other than standard chess
+type VariantRating struct {
+	ModelID int    `json:"model_id" db:"model_id"`
+	Variant string `json:"variant" db:"variant"`
+	Rating  int    `json:"rating" db:"rating"`
+}
@@ -0,0 +1,22 @@
+package model
+
+// Chess variants
+const (
+	VariantStandard      = "standard"
+	VariantChess960      = "chess960"
+	VariantKingOfTheHill = "kingofthehill"
+	VariantThreeCheck    = "threecheck"
+)
+
+// Variants lists every supported variant
+var Variants = []string{VariantStandard, VariantChess960, VariantKingOfTheHill, VariantThreeCheck}
+
+// ValidVariant reports whether variant is supported
+func ValidVariant(variant string) bool {
+	for _, v := range Variants {
+		if v == variant {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hash returns the hex SHA-256 of a token, such as a refresh token or an API
+// key. Tokens are random, so a fast unsalted hash is enough to keep stored
+// values useless if leaked; they don't need a slow password hash.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,8 +3,9 @@ package server
 import (
 	"context"
 	"errors"
-	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/ajlaz/checkmAIt/server/api"
 	"github.com/ajlaz/checkmAIt/server/config"
@@ -14,28 +15,28 @@ import (
 type Server struct {
 	config *config.Config
 
+	// Built up front so Shutdown can run before or while Start does
 	http *http.Server
 }
 
-func New(config *config.Config) *Server {
+func New(config *config.Config, api *api.API) *Server {
 	return &Server{
 		config: config,
+		http: &http.Server{
+			Handler: api,
+			Addr:    net.JoinHostPort(config.HTTP.Host, strconv.Itoa(config.HTTP.Port)),
+		},
 	}
 }
 
-func (s *Server) Start(api *api.API) error {
-	s.http = &http.Server{
-		Handler: api,
-		Addr:    fmt.Sprintf("%s:%s", s.config.HTTP.Host, s.config.HTTP.Port),
-	}
-
+func (s *Server) Start() error {
 	return s.http.ListenAndServe()
 }
 
-func (s *Server) Run(ctx context.Context, api *api.API) {
+func (s *Server) Run(ctx context.Context) {
 	logger := logging.FromContext(ctx)
 	logger.Debug().Ctx(ctx).Msg("Running server...")
-	if err := s.Start(api); err != nil {
+	if err := s.Start(); err != nil {
 		if !errors.Is(err, http.ErrServerClosed) {
 			logger.Err(err).Ctx(ctx).Send()
 		}
@@ -0,0 +1,103 @@
+package api_key
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/apikeys"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/secrets"
+)
+
+// displayPrefixLength is how much of a key is kept to identify it in listings
+const displayPrefixLength = len(model.APIKeyPrefix) + 8
+
+// ErrInvalidAPIKey is returned when an API key is unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// CreateAPIKey creates a key for a user with the given scopes. The key itself
+// is only returned here; afterwards only its hash is kept.
+func (s *Service) CreateAPIKey(ctx context.Context, userID int, name string, scopes []string) (*model.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", errors.New("API key name cannot be empty")
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.New("API key needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !model.ValidScope(scope) {
+			return nil, "", fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := model.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	created, err := s.apiKeyStore.CreateAPIKey(ctx, &model.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  key[:displayPrefixLength],
+		KeyHash: secrets.Hash(key),
+		Scopes:  scopes,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return created, key, nil
+}
+
+// ListAPIKeys retrieves a user's API keys, including revoked ones
+func (s *Service) ListAPIKeys(ctx context.Context, userID int) ([]*model.APIKey, error) {
+	if userID == 0 {
+		return nil, errors.New("user ID cannot be empty")
+	}
+
+	return s.apiKeyStore.GetAPIKeysByUserID(ctx, userID)
+}
+
+// RevokeAPIKey revokes one of a user's API keys
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, keyID int) error {
+	return s.apiKeyStore.RevokeAPIKey(ctx, keyID, userID)
+}
+
+// AuthenticateAPIKey returns the active API key matching key and records its
+// use. Keys of banned users are rejected.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	if !strings.HasPrefix(key, model.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyStore.GetAPIKeyByHash(ctx, secrets.Hash(key))
+	if errors.Is(err, apikeys.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	owner, err := s.userStore.GetUserByID(ctx, strconv.Itoa(apiKey.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if owner.Banned() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := s.apiKeyStore.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
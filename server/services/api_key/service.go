@@ -0,0 +1,31 @@
+package api_key
+
+import (
+	"context"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/apikeys"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/users"
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// ServiceInterface defines the contract for the API key service
+type ServiceInterface interface {
+	CreateAPIKey(ctx context.Context, userID int, name string, scopes []string) (*model.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID int) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID int) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error)
+}
+
+// Service implements the API key service
+type Service struct {
+	apiKeyStore apikeys.StoreInterface
+	userStore   users.StoreInterface
+}
+
+// NewService creates a new API key service instance
+func NewService(apiKeyStore apikeys.StoreInterface, userStore users.StoreInterface) ServiceInterface {
+	return &Service{
+		apiKeyStore: apiKeyStore,
+		userStore:   userStore,
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+)
+
+const (
+	// healthCheckInterval is how often every engine instance is probed
+	healthCheckInterval = 10 * time.Second
+
+	// healthCheckTimeout bounds a single probe
+	healthCheckTimeout = 3 * time.Second
+)
+
+// gamesResponse matches the engine's GET /games response
+type gamesResponse struct {
+	Games []string `json:"games"`
+}
+
+// StartHealthChecks probes every instance immediately and then periodically
+// until ctx is cancelled. It blocks, so callers should run it in a goroutine.
+func (s *Service) StartHealthChecks(ctx context.Context) {
+	s.checkAll(ctx)
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkAll(ctx)
+		}
+	}
+}
+
+// Instances returns the current status of every engine instance
+func (s *Service) Instances() []InstanceStatus {
+	now := time.Now()
+
+	statuses := make([]InstanceStatus, 0, len(s.instances))
+	for _, inst := range s.instances {
+		statuses = append(statuses, inst.status(now))
+	}
+
+	return statuses
+}
+
+// CheckHealth asks every instance for its health concurrently and succeeds if
+// at least one of them is healthy, since games can still be placed on it
+func (s *Service) CheckHealth(ctx context.Context) error {
+	errs := make([]error, len(s.instances))
+
+	var wg sync.WaitGroup
+	for i, inst := range s.instances {
+		wg.Add(1)
+		go func(i int, inst *instance) {
+			defer wg.Done()
+			if err := s.get(ctx, inst.url+"/health", nil); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", inst.url, err)
+			}
+		}(i, inst)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no healthy engine instance: %w", errors.Join(errs...))
+}
+
+// checkAll probes every instance concurrently
+func (s *Service) checkAll(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	var wg sync.WaitGroup
+	for _, inst := range s.instances {
+		wg.Add(1)
+		go func(inst *instance) {
+			defer wg.Done()
+
+			activeGames, err := s.probe(ctx, inst)
+			if err != nil {
+				logger.Warn().Err(err).Str("engine", inst.url).Msg("Engine health check failed")
+			}
+			inst.recordHealth(err == nil, activeGames, time.Now())
+		}(inst)
+	}
+	wg.Wait()
+}
+
+// probe checks an instance's health endpoint and returns its number of games
+func (s *Service) probe(ctx context.Context, inst *instance) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	if err := s.get(ctx, inst.url+"/health", nil); err != nil {
+		return 0, err
+	}
+
+	var games gamesResponse
+	if err := s.get(ctx, inst.url+"/games", &games); err != nil {
+		return 0, err
+	}
+
+	return len(games.Games), nil
+}
+
+// get performs a GET request and decodes the JSON body into out if non-nil
+func (s *Service) get(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach engine: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("engine returned status %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// failureThreshold is the number of consecutive failures that opens an
+	// instance's circuit breaker
+	failureThreshold = 3
+
+	// breakerCooldown is how long an open circuit rejects games before a
+	// single trial request is let through; further games wait for its outcome
+	breakerCooldown = 30 * time.Second
+)
+
+// InstanceStatus is a snapshot of an engine instance's health and load
+type InstanceStatus struct {
+	URL                 string    `json:"url"`
+	Healthy             bool      `json:"healthy"`
+	CircuitOpen         bool      `json:"circuitOpen"`
+	ActiveGames         int       `json:"activeGames"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastChecked         time.Time `json:"lastChecked"`
+}
+
+// instance tracks the state of a single engine endpoint
+type instance struct {
+	url string
+
+	mu                  sync.Mutex
+	healthy             bool
+	activeGames         int
+	consecutiveFailures int
+	openUntil           time.Time // Circuit is open while now is before openUntil
+	halfOpenInFlight    bool      // A trial request is testing a half-open circuit
+	lastChecked         time.Time
+}
+
+func newInstance(url string) *instance {
+	// Instances are assumed healthy until the first health check says otherwise
+	return &instance{
+		url:     url,
+		healthy: true,
+	}
+}
+
+// available reports whether the instance may receive a new game. Once the
+// breaker cooldown has passed the instance is half-open, and is only
+// available while no trial request is in flight.
+func (i *instance) available(now time.Time) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.healthy && !now.Before(i.openUntil) && !i.halfOpenInFlight
+}
+
+// acquire claims the instance for a request. A half-open instance lets a
+// single trial request through; its outcome, passed to recordSuccess or
+// recordFailure, closes or reopens the circuit.
+func (i *instance) acquire(now time.Time) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.healthy || now.Before(i.openUntil) || i.halfOpenInFlight {
+		return false
+	}
+	if !i.openUntil.IsZero() {
+		i.halfOpenInFlight = true
+	}
+	return true
+}
+
+// load returns the number of games believed to be running on the instance
+func (i *instance) load() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.activeGames
+}
+
+// addGame counts a newly created game until the next health check refreshes
+// the real number
+func (i *instance) addGame() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.activeGames++
+}
+
+// recordSuccess closes the circuit after a successful request
+func (i *instance) recordSuccess() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.consecutiveFailures = 0
+	i.openUntil = time.Time{}
+	i.halfOpenInFlight = false
+}
+
+// release gives up a claim without an outcome, such as a request its caller
+// cancelled, so a half-open instance may run another trial
+func (i *instance) release() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.halfOpenInFlight = false
+}
+
+// recordFailure counts a failed request and opens the circuit once the
+// failure threshold is reached
+func (i *instance) recordFailure(now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.halfOpenInFlight = false
+	i.consecutiveFailures++
+	if i.consecutiveFailures >= failureThreshold {
+		i.openUntil = now.Add(breakerCooldown)
+	}
+}
+
+// recordHealth stores the outcome of a health check
+func (i *instance) recordHealth(healthy bool, activeGames int, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.healthy = healthy
+	i.lastChecked = now
+	if healthy {
+		i.activeGames = activeGames
+	}
+}
+
+// status returns a snapshot of the instance
+func (i *instance) status(now time.Time) InstanceStatus {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return InstanceStatus{
+		URL:                 i.url,
+		Healthy:             i.healthy,
+		CircuitOpen:         now.Before(i.openUntil),
+		ActiveGames:         i.activeGames,
+		ConsecutiveFailures: i.consecutiveFailures,
+		LastChecked:         i.lastChecked,
+	}
+}
@@ -2,29 +2,74 @@ package engine
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer records spans for calls to the engine
+var tracer = otel.Tracer("github.com/ajlaz/checkmAIt/server/services/engine")
+
+// errRejected is returned when an engine answers a request with a 4xx
+// status. The engine is up, so the rejection doesn't count against its
+// circuit breaker.
+var errRejected = errors.New("engine rejected the request")
+
+// requestIDHeader forwards the ID of the request that started a game
+const requestIDHeader = "X-Request-ID"
+
 // ServiceInterface defines the contract for the engine service
 type ServiceInterface interface {
-	CreateGame(gameID, player1ID, player1ModelID, player2ID, player2ModelID string) (string, int, error)
+	CreateGame(ctx context.Context, gameID, player1ID, player1ModelID, player2ID, player2ModelID string, options GameOptions) (*Game, error)
+	DeleteGame(ctx context.Context, engineURL, gameID string) error
+	ListGames(ctx context.Context, engineURL string) ([]string, error)
+	GameSocketURL(engineURL string, wsPort int, gameID, playerID, color string) (string, error)
+	StartHealthChecks(ctx context.Context)
+	Instances() []InstanceStatus
+	CheckHealth(ctx context.Context) error
 }
 
-// Service implements the engine service
+// Service implements the engine service over a pool of engine instances
 type Service struct {
-	engineURL  string
+	instances  []*instance
 	httpClient *http.Client
 }
 
+// Game describes a game created on one of the engine instances
+type Game struct {
+	ID     string `json:"gameId"`
+	WSPort int    `json:"wsPort"`
+	Engine string `json:"engine"` // Base URL of the engine hosting the game
+}
+
+// GameOptions configures how a new game is played
+type GameOptions struct {
+	StartFEN string // Defaults to the standard initial position
+	Variant  string // Defaults to standard chess
+}
+
 // CreateGameRequest matches the engine's expected request format
 type CreateGameRequest struct {
-	GameID         string `json:"gameId"`
-	WhitePlayerID  string `json:"whitePlayerId"`
-	BlackPlayerID  string `json:"blackPlayerId"`
+	GameID        string `json:"gameId"`
+	WhitePlayerID string `json:"whitePlayerId"`
+	BlackPlayerID string `json:"blackPlayerId"`
+	StartFEN      string `json:"startFen,omitempty"` // Defaults to the standard initial position
+	Variant       string `json:"variant,omitempty"`  // Defaults to standard chess
 }
 
 // CreateGameResponse matches the engine's response format
@@ -35,54 +80,238 @@ type CreateGameResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
-// NewService creates a new engine service
-func NewService(engineURL string) ServiceInterface {
+// NewService creates a new engine service balancing games across engineURLs.
+// Every call to an engine is cut off after timeout.
+func NewService(engineURLs []string, timeout time.Duration) ServiceInterface {
+	instances := make([]*instance, 0, len(engineURLs))
+	for _, engineURL := range engineURLs {
+		instances = append(instances, newInstance(engineURL))
+	}
+
 	return &Service{
-		engineURL: engineURL,
+		instances: instances,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: timeout,
 		},
 	}
 }
 
-// CreateGame creates a new game in the engine and returns the game ID and WebSocket port
-func (s *Service) CreateGame(gameID, player1ID, player1ModelID, player2ID, player2ModelID string) (string, int, error) {
+// CreateGame creates a new game on the least-loaded available engine. If the
+// engine fails or rejects the game, it is retried on the next candidate.
+// Only transport errors and 5xx responses count as circuit failures. The request ID in
+// ctx, if any, is forwarded so the game can be traced across services.
+func (s *Service) CreateGame(ctx context.Context, gameID, player1ID, player1ModelID, player2ID, player2ModelID string, options GameOptions) (*Game, error) {
+	ctx, span := tracer.Start(ctx, "engine.CreateGame", trace.WithAttributes(
+		attribute.String("game.id", gameID),
+		attribute.String("game.variant", options.Variant),
+	))
+	defer span.End()
+
+	candidates := s.candidates()
+	if len(candidates) == 0 {
+		metrics.EngineCreateGameErrors.WithLabelValues("none").Inc()
+		err := errors.New("no engine instances available")
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var errs []error
+	for _, inst := range candidates {
+		// Another request may have claimed a half-open instance's trial
+		if !inst.acquire(time.Now()) {
+			continue
+		}
+
+		game, err := s.createGameOn(ctx, inst, gameID, player1ID, player2ID, options)
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			// The caller gave up; that says nothing about the engine
+			inst.release()
+			span.SetStatus(codes.Error, ctx.Err().Error())
+			return nil, ctx.Err()
+		case errors.Is(err, errRejected):
+			metrics.EngineCreateGameErrors.WithLabelValues(inst.url).Inc()
+			inst.recordSuccess()
+			errs = append(errs, fmt.Errorf("%s: %w", inst.url, err))
+			continue
+		default:
+			metrics.EngineCreateGameErrors.WithLabelValues(inst.url).Inc()
+			inst.recordFailure(time.Now())
+			errs = append(errs, fmt.Errorf("%s: %w", inst.url, err))
+			continue
+		}
+
+		inst.recordSuccess()
+		inst.addGame()
+		span.SetAttributes(attribute.String("engine.url", inst.url))
+		return game, nil
+	}
+
+	// Every candidate was a half-open instance already running its trial
+	if len(errs) == 0 {
+		metrics.EngineCreateGameErrors.WithLabelValues("none").Inc()
+		err := errors.New("no engine instances available")
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	err := fmt.Errorf("all engine instances failed: %w", errors.Join(errs...))
+	span.SetStatus(codes.Error, err.Error())
+	return nil, err
+}
+
+// candidates returns the instances that may receive a game, least loaded first
+func (s *Service) candidates() []*instance {
+	now := time.Now()
+
+	var available []*instance
+	for _, inst := range s.instances {
+		if inst.available(now) {
+			available = append(available, inst)
+		}
+	}
+
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].load() < available[j].load()
+	})
+
+	return available
+}
+
+// createGameOn asks a single engine instance to create the game
+func (s *Service) createGameOn(ctx context.Context, inst *instance, gameID, whitePlayerID, blackPlayerID string, options GameOptions) (game *Game, err error) {
+	ctx, span := tracer.Start(ctx, "POST /game/create", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("engine.url", inst.url),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	// Create request payload
 	reqBody := CreateGameRequest{
-		GameID:         gameID,
-		WhitePlayerID:  player1ID,
-		BlackPlayerID:  player2ID,
+		GameID:        gameID,
+		WhitePlayerID: whitePlayerID,
+		BlackPlayerID: blackPlayerID,
+		StartFEN:      options.StartFEN,
+		Variant:       options.Variant,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Make HTTP request to engine
-	url := fmt.Sprintf("%s/game/create", s.engineURL)
-	resp, err := s.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	url := fmt.Sprintf("%s/game/create", inst.url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if requestID := logging.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(requestIDHeader, requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to make request to engine: %w", err)
+		return nil, fmt.Errorf("failed to make request to engine: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("engine returned status %d", resp.StatusCode)
 	}
 
-	// Parse response
+	// Parse response; a rejection's body carries the reason if it parses
 	var response CreateGameResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", 0, fmt.Errorf("failed to parse response: %w", err)
+	parseErr := json.Unmarshal(body, &response)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w with status %d: %s", errRejected, resp.StatusCode, response.Error)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", parseErr)
 	}
 
 	// Check if request was successful
 	if !response.Success {
-		return "", 0, fmt.Errorf("engine returned error: %s", response.Error)
+		return nil, fmt.Errorf("engine returned error: %s", response.Error)
+	}
+
+	return &Game{
+		ID:     response.GameID,
+		WSPort: response.WSPort,
+		Engine: inst.url,
+	}, nil
+}
+
+// GameSocketURL returns the WebSocket address for a player in a game hosted
+// by engineURL. Engines serve each game on its own port on the same host as
+// their HTTP API.
+func (s *Service) GameSocketURL(engineURL string, wsPort int, gameID, playerID, color string) (string, error) {
+	base, err := url.Parse(engineURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid engine URL: %w", err)
+	}
+
+	scheme := "ws"
+	if base.Scheme == "https" {
+		scheme = "wss"
+	}
+
+	query := url.Values{}
+	query.Set("gameId", gameID)
+	query.Set("playerId", playerID)
+	query.Set("color", color)
+
+	socketURL := url.URL{
+		Scheme:   scheme,
+		Host:     base.Hostname() + ":" + strconv.Itoa(wsPort),
+		Path:     "/",
+		RawQuery: query.Encode(),
+	}
+
+	return socketURL.String(), nil
+}
+
+// DeleteGame removes a game from the engine instance hosting it
+func (s *Service) DeleteGame(ctx context.Context, engineURL, gameID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/game/%s", engineURL, url.PathEscape(gameID)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to engine: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("engine returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ListGames returns the IDs of the live games on an engine instance
+func (s *Service) ListGames(ctx context.Context, engineURL string) ([]string, error) {
+	var games gamesResponse
+	if err := s.get(ctx, engineURL+"/games", &games); err != nil {
+		return nil, err
 	}
 
-	return response.GameID, response.WSPort, nil
+	return games.Games, nil
 }
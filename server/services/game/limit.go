@@ -0,0 +1,31 @@
+package game
+
+import (
+	"context"
+	"time"
+)
+
+// dailyLimitWindow is the period the daily game limit counts games over
+const dailyLimitWindow = 24 * time.Hour
+
+// DailyLimitWait returns how long until the oldest of the user's games
+// counting towards the limit leaves the window, once they have reached it.
+// Only completed games are recorded, so they alone count: matches that are
+// cancelled, expire or end in an error don't, and neither does a game still
+// being played, which can take a user one game past the limit.
+func (s *Service) DailyLimitWait(ctx context.Context, userID int) (time.Duration, error) {
+	if s.maxGamesPerDay <= 0 {
+		return 0, nil
+	}
+
+	now := s.now()
+	endedAt, err := s.gameStore.GetRecentGameEnd(ctx, userID, now.Add(-dailyLimitWindow), s.maxGamesPerDay)
+	if err != nil {
+		return 0, err
+	}
+	if endedAt == nil {
+		return 0, nil
+	}
+
+	return max(endedAt.Add(dailyLimitWindow).Sub(now), 0), nil
+}
@@ -0,0 +1,65 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/metrics"
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// RecordGame stores a finished game and updates both models' ratings
+func (s *Service) RecordGame(ctx context.Context, g *model.Game) (*model.Game, error) {
+	if g.GameID == "" {
+		return nil, errors.New("game ID cannot be empty")
+	}
+
+	if g.Variant == "" {
+		g.Variant = model.VariantStandard
+	}
+
+	recorded, err := s.gameStore.CreateGame(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ratings are kept separately for every variant
+	outcome := metrics.OutcomeDecisive
+	switch g.Result {
+	case model.ResultWhite:
+		err = s.modelService.UpdateVariantRating(ctx, g.Variant, g.WhiteModelID, g.BlackModelID)
+	case model.ResultBlack:
+		err = s.modelService.UpdateVariantRating(ctx, g.Variant, g.BlackModelID, g.WhiteModelID)
+	case model.ResultDraw:
+		outcome = metrics.OutcomeDraw
+		err = s.modelService.UpdateVariantRatingDraw(ctx, g.Variant, g.WhiteModelID, g.BlackModelID)
+	default:
+		err = fmt.Errorf("unknown game result: %s", g.Result)
+	}
+
+	if err != nil {
+		return recorded, fmt.Errorf("failed to update ratings: %w", err)
+	}
+	metrics.RatingUpdates.WithLabelValues(g.Variant, outcome).Inc()
+
+	return recorded, nil
+}
+
+// GetGamesByUserID retrieves every game a user has played
+func (s *Service) GetGamesByUserID(ctx context.Context, userID int) ([]*model.Game, error) {
+	if userID == 0 {
+		return nil, errors.New("user ID cannot be empty")
+	}
+
+	return s.gameStore.GetGamesByUserID(ctx, userID)
+}
+
+// GetUserStats aggregates the results of every game a user has played
+func (s *Service) GetUserStats(ctx context.Context, userID int) (*model.UserStats, error) {
+	if userID == 0 {
+		return nil, errors.New("user ID cannot be empty")
+	}
+
+	return s.gameStore.GetUserStats(ctx, userID)
+}
@@ -0,0 +1,40 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/games"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/user_model"
+)
+
+// ServiceInterface defines the contract for the game history service
+type ServiceInterface interface {
+	RecordGame(ctx context.Context, game *model.Game) (*model.Game, error)
+	GetGamesByUserID(ctx context.Context, userID int) ([]*model.Game, error)
+	GetUserStats(ctx context.Context, userID int) (*model.UserStats, error)
+
+	// DailyLimitWait returns how long userID must wait until they are under
+	// their daily game limit again, or zero if they may play now. Only
+	// completed games count towards the limit.
+	DailyLimitWait(ctx context.Context, userID int) (time.Duration, error)
+}
+
+// Service records finished games and applies their rating changes
+type Service struct {
+	gameStore      games.StoreInterface
+	modelService   user_model.ServiceInterface
+	maxGamesPerDay int // Games each user may finish in any 24 hours; 0 disables the limit
+	now            func() time.Time
+}
+
+// NewService creates a new game service instance
+func NewService(gameStore games.StoreInterface, modelService user_model.ServiceInterface, maxGamesPerDay int) ServiceInterface {
+	return &Service{
+		gameStore:      gameStore,
+		modelService:   modelService,
+		maxGamesPerDay: maxGamesPerDay,
+		now:            time.Now,
+	}
+}
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/ajlaz/checkmAIt/server/services/engine"
+	"github.com/pressly/goose"
+	"github.com/redis/go-redis/v9"
+)
+
+// Database checks that the database answers a ping
+func Database(db *sql.DB) Check {
+	return Check{
+		Name: "database",
+		Run:  db.PingContext,
+	}
+}
+
+// Redis checks that the matchmaking store's Redis server answers a ping
+func Redis(client *redis.Client) Check {
+	return Check{
+		Name: "redis",
+		Run: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
+
+// Engine checks that at least one engine instance is healthy
+func Engine(engineService engine.ServiceInterface) Check {
+	return Check{
+		Name: "engine",
+		Run:  engineService.CheckHealth,
+	}
+}
+
+// Migrations checks that the database schema is at the version of the
+// newest migration in dir, which is read once when the check is created
+func Migrations(db *sql.DB, dir string) Check {
+	expected, expectedErr := latestMigration(dir)
+
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			if expectedErr != nil {
+				return expectedErr
+			}
+
+			current, err := dbVersion(ctx, db)
+			if err != nil {
+				return fmt.Errorf("failed to read schema version: %w", err)
+			}
+			if current != expected {
+				return fmt.Errorf("schema is at version %d, expected %d", current, expected)
+			}
+			return nil
+		},
+	}
+}
+
+// latestMigration returns the version of the newest migration in dir
+func latestMigration(dir string) (int64, error) {
+	migrations, err := goose.CollectMigrations(dir, 0, math.MaxInt64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	return last.Version, nil
+}
+
+// dbVersion reads the schema version goose recorded. goose doesn't take a
+// context, so the query is abandoned rather than cancelled if ctx ends first.
+func dbVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	type result struct {
+		version int64
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		version, err := goose.GetDBVersion(db)
+		done <- result{version, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case r := <-done:
+		return r.version, r.err
+	}
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check is a named readiness check of one dependency
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every readiness check
+type Report struct {
+	Status    string    `json:"status"`
+	Checks    []Result  `json:"checks"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// OK reports whether every check passed
+func (r *Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// ServiceInterface defines the contract for the health service
+type ServiceInterface interface {
+	// Ready runs every check, or returns the previous report if it is
+	// recent enough
+	Ready(ctx context.Context) *Report
+}
+
+// Config controls how checks are run
+type Config struct {
+	Timeout  time.Duration // Bounds each check
+	CacheTTL time.Duration // How long a report is reused
+}
+
+// Service runs readiness checks and caches their report so frequent probes
+// don't hammer the dependencies
+type Service struct {
+	checks []Check
+	config Config
+
+	mu   sync.Mutex // Held while checks run so concurrent probes share a run
+	last *Report
+}
+
+// NewService creates a new health service running checks
+func NewService(cfg Config, checks ...Check) ServiceInterface {
+	return &Service{
+		checks: checks,
+		config: cfg,
+	}
+}
+
+// Ready runs every check concurrently, or returns the previous report if it
+// is younger than the cache TTL
+func (s *Service) Ready(ctx context.Context) *Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last != nil && time.Since(s.last.CheckedAt) < s.config.CacheTTL {
+		return s.last
+	}
+
+	report := &Report{
+		Status:    StatusOK,
+		Checks:    make([]Result, len(s.checks)),
+		CheckedAt: time.Now(),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = s.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	s.last = report
+	return report
+}
+
+// run runs a single check under the configured timeout
+func (s *Service) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
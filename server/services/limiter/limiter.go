@@ -0,0 +1,128 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// Attempt starts an attempt by key. If key is backing off or locked out it
+// returns how long is left and records nothing. Otherwise the attempt is
+// counted as a failure up front, in the same step as the check, so
+// concurrent attempts can't all get through before their failures are
+// recorded. Attempts that turn out not to fail are taken back with Forgive.
+// If the attempt locks key out, the lockout is returned for Fail to record
+// once the attempt has actually failed.
+func (s *Service) Attempt(ctx context.Context, key string) (time.Duration, *model.Lockout, error) {
+	now := s.now()
+	var waited time.Duration
+	var lockout *model.Lockout
+
+	_, err := s.store.UpdateLimiterState(ctx, s.storeKey(key), func(state *model.LimiterState) {
+		if waited = wait(state, now); waited > 0 {
+			return
+		}
+		if state.LastFailureAt != nil && now.Sub(*state.LastFailureAt) > s.policy.Window {
+			state.Failures = 0
+		}
+
+		state.Failures++
+		state.LastFailureAt = &now
+
+		delay, lockedOut := s.policy.delay(state.Failures)
+		if delay <= 0 {
+			return
+		}
+
+		until := now.Add(delay)
+		state.LockedUntil = &until
+		if lockedOut {
+			lockout = &model.Lockout{
+				Limiter:     s.name,
+				Key:         key,
+				Failures:    state.Failures,
+				LockedUntil: until,
+			}
+		}
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return waited, lockout, nil
+}
+
+// Fail confirms that an attempt counted by Attempt failed, recording the
+// lockout it caused, if any
+func (s *Service) Fail(ctx context.Context, lockout *model.Lockout) error {
+	if lockout == nil {
+		return nil
+	}
+	return s.store.RecordLockout(ctx, lockout)
+}
+
+// Forgive takes back an attempt counted by Attempt that did not fail, and
+// shortens any wait to what the remaining failures call for
+func (s *Service) Forgive(ctx context.Context, key string) error {
+	_, err := s.store.UpdateLimiterState(ctx, s.storeKey(key), func(state *model.LimiterState) {
+		if state.Failures > 0 {
+			state.Failures--
+		}
+		if state.LockedUntil == nil || state.LastFailureAt == nil {
+			return
+		}
+
+		delay, _ := s.policy.delay(state.Failures)
+		if until := state.LastFailureAt.Add(delay); until.Before(*state.LockedUntil) {
+			state.LockedUntil = &until
+		}
+	})
+	return err
+}
+
+// Reset forgets key's failed attempts
+func (s *Service) Reset(ctx context.Context, key string) error {
+	return s.store.DeleteLimiterState(ctx, s.storeKey(key))
+}
+
+// Lockouts lists the most recent lockouts
+func (s *Service) Lockouts(ctx context.Context, limit int) ([]*model.Lockout, error) {
+	return s.store.ListLockouts(ctx, limit)
+}
+
+// storeKey namespaces key so limiters can share a store
+func (s *Service) storeKey(key string) string {
+	return s.name + ":" + key
+}
+
+// delay returns how long a key has to wait after its nth consecutive failure
+// and whether that wait is a lockout
+func (p Policy) delay(failures int) (time.Duration, bool) {
+	if p.LockoutAttempts > 0 && failures >= p.LockoutAttempts {
+		return p.LockoutDuration, true
+	}
+
+	over := failures - p.FreeAttempts
+	if over <= 0 || p.BackoffBase <= 0 {
+		return 0, false
+	}
+
+	delay := p.BackoffBase
+	for i := 1; i < over && delay < p.BackoffMax; i++ {
+		delay *= 2
+	}
+	if p.BackoffMax > 0 && delay > p.BackoffMax {
+		delay = p.BackoffMax
+	}
+
+	return delay, false
+}
+
+// wait returns how long is left on a key's backoff or lockout
+func wait(state *model.LimiterState, now time.Time) time.Duration {
+	if state.LockedUntil == nil || !state.LockedUntil.After(now) {
+		return 0
+	}
+	return state.LockedUntil.Sub(now)
+}
@@ -0,0 +1,128 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/limiter"
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// maxMemoryLockouts is how many lockout records the memory store keeps
+const maxMemoryLockouts = 1000
+
+// sweepInterval is how often expired states are forgotten
+const sweepInterval = time.Minute
+
+// MemoryStore keeps limiter state in process. It suits a single server
+// instance; state is lost on restart and not shared between instances.
+type MemoryStore struct {
+	mu        sync.Mutex
+	window    time.Duration
+	states    map[string]model.LimiterState
+	lockouts  []*model.Lockout
+	nextID    int
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory limiter store for limiters that
+// forget failures after window
+func NewMemoryStore(window time.Duration) limiter.StoreInterface {
+	return &MemoryStore{
+		window: window,
+		states: make(map[string]model.LimiterState),
+		now:    time.Now,
+	}
+}
+
+// GetLimiterState returns a copy of a key's state
+func (m *MemoryStore) GetLimiterState(_ context.Context, key string) (*model.LimiterState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[key]
+	if !ok {
+		state = model.LimiterState{Key: key}
+	}
+	return &state, nil
+}
+
+// UpdateLimiterState applies update to a key's state while holding the lock
+func (m *MemoryStore) UpdateLimiterState(_ context.Context, key string, update func(*model.LimiterState)) (*model.LimiterState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(m.now())
+
+	state, ok := m.states[key]
+	if !ok {
+		state = model.LimiterState{Key: key}
+	}
+	update(&state)
+	m.states[key] = state
+
+	return &state, nil
+}
+
+// DeleteLimiterState forgets a key's state
+func (m *MemoryStore) DeleteLimiterState(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.states, key)
+	return nil
+}
+
+// sweep forgets states whose failures are outside the window and whose
+// backoff or lockout has ended, which behave exactly like no state, so keys
+// that stopped failing don't accumulate. It runs at most once per
+// sweepInterval. Callers must hold m.mu.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, state := range m.states {
+		if state.LastFailureAt != nil && now.Sub(*state.LastFailureAt) <= m.window {
+			continue
+		}
+		if state.LockedUntil != nil && state.LockedUntil.After(now) {
+			continue
+		}
+		delete(m.states, key)
+	}
+}
+
+// RecordLockout keeps a lockout record, dropping the oldest once
+// maxMemoryLockouts are held
+func (m *MemoryStore) RecordLockout(_ context.Context, lockout *model.Lockout) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	record := *lockout
+	record.ID = m.nextID
+	record.CreatedAt = time.Now()
+
+	m.lockouts = append(m.lockouts, &record)
+	if len(m.lockouts) > maxMemoryLockouts {
+		m.lockouts = m.lockouts[len(m.lockouts)-maxMemoryLockouts:]
+	}
+	return nil
+}
+
+// ListLockouts returns the most recent lockouts, newest first
+func (m *MemoryStore) ListLockouts(_ context.Context, limit int) ([]*model.Lockout, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lockouts := []*model.Lockout{}
+	for i := len(m.lockouts) - 1; i >= 0 && len(lockouts) < limit; i-- {
+		record := *m.lockouts[i]
+		lockouts = append(lockouts, &record)
+	}
+	return lockouts, nil
+}
@@ -0,0 +1,63 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/limiter"
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// ServiceInterface defines the contract for a failed-attempt limiter
+type ServiceInterface interface {
+	// Attempt starts an attempt by key. It returns how long key must wait if
+	// it is backing off or locked out; otherwise the attempt is counted as a
+	// failure until it is forgiven, and the lockout it would cause is
+	// returned for Fail.
+	Attempt(ctx context.Context, key string) (time.Duration, *model.Lockout, error)
+
+	// Fail confirms an attempt failed and records the lockout it caused
+	Fail(ctx context.Context, lockout *model.Lockout) error
+
+	// Forgive takes back an attempt that did not fail
+	Forgive(ctx context.Context, key string) error
+
+	// Reset forgets key's failed attempts, typically after a success
+	Reset(ctx context.Context, key string) error
+
+	// Lockouts lists the most recent lockouts recorded in the limiter's
+	// store, newest first
+	Lockouts(ctx context.Context, limit int) ([]*model.Lockout, error)
+}
+
+// Policy decides how long a key has to wait after failed attempts. Past
+// FreeAttempts each failure doubles the wait, starting at BackoffBase and
+// capped at BackoffMax; reaching LockoutAttempts locks the key out for
+// LockoutDuration and records an audit entry once that attempt has failed.
+type Policy struct {
+	FreeAttempts    int           // Failures allowed without waiting
+	BackoffBase     time.Duration // Wait after the first failure past FreeAttempts
+	BackoffMax      time.Duration // Longest backoff wait
+	LockoutAttempts int           // Failures that lock the key out; 0 disables lockouts
+	LockoutDuration time.Duration // How long a lockout lasts
+	Window          time.Duration // Failures are forgotten after this long without another
+}
+
+// Service limits failed attempts per key
+type Service struct {
+	name   string
+	store  limiter.StoreInterface
+	policy Policy
+	now    func() time.Time
+}
+
+// NewService creates a limiter named name, which namespaces its keys in
+// store and identifies it in lockout records
+func NewService(name string, store limiter.StoreInterface, policy Policy) ServiceInterface {
+	return &Service{
+		name:   name,
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
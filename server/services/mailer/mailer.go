@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ajlaz/checkmAIt/server/config"
+)
+
+// Mail drivers
+const (
+	DriverSMTP   = "smtp"
+	DriverOutbox = "outbox"
+	DriverLog    = "log"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New creates the mailer selected by cfg.Driver
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case DriverOutbox:
+		return NewOutboxMailer(cfg.OutboxDir, cfg.From)
+	case DriverLog, "":
+		return NewLogMailer(os.Stdout, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
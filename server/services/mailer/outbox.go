@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// unsafeFileChars matches characters kept out of outbox file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9@._-]`)
+
+// OutboxMailer writes every message to a .eml file instead of sending it,
+// for local development and tests
+type OutboxMailer struct {
+	dir  string
+	from string
+}
+
+// NewOutboxMailer creates a mailer writing to dir, creating it if needed
+func NewOutboxMailer(dir, from string) (Mailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("outbox mailer needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail outbox: %w", err)
+	}
+
+	return &OutboxMailer{dir: dir, from: from}, nil
+}
+
+// Send writes msg to a file named after the time and recipient
+func (m *OutboxMailer) Send(msg Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	if err := os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg), 0o644); err != nil {
+		return fmt.Errorf("failed to write email to outbox: %w", err)
+	}
+	return nil
+}
+
+// LogMailer prints every message instead of sending it
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewLogMailer creates a mailer printing messages to w
+func NewLogMailer(w io.Writer, from string) Mailer {
+	return &LogMailer{w: w, from: from}
+}
+
+// Send prints msg followed by a separator line
+func (m *LogMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := fmt.Fprintf(m.w, "%s\r\n-----\r\n", format(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to log email: %w", err)
+	}
+	return nil
+}
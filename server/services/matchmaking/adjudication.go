@@ -0,0 +1,198 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// ReasonAdjudication is the result reason recorded when the server ends a
+// game by rule instead of the engine. The rule that applied is recorded
+// separately in GameResult.Adjudication.
+const ReasonAdjudication = "adjudication"
+
+// Adjudication rules
+const (
+	AdjudicationMoveCap              = "move_cap"
+	AdjudicationInsufficientMaterial = "insufficient_material"
+	AdjudicationMaterialImbalance    = "material_imbalance"
+)
+
+// AdjudicationConfig holds the rules used to end long or dead games. Zero
+// values disable the corresponding rule.
+type AdjudicationConfig struct {
+	MoveCap              int  // Full moves after which the game is drawn
+	InsufficientMaterial bool // Draw when neither side can mate, including bare kings
+	MaterialThreshold    int  // Material lead in pawns that counts as decisive
+	MaterialPlies        int  // Consecutive plies the lead must be held to win
+}
+
+// pieceValues is the material value of each piece in pawns
+var pieceValues = map[rune]int{'p': 1, 'n': 3, 'b': 3, 'r': 5, 'q': 9}
+
+// imbalanceStreak tracks how long one side has held a decisive material lead
+type imbalanceStreak struct {
+	leader string
+	plies  int
+}
+
+// position is the part of a FEN position adjudication looks at
+type position struct {
+	white, black int   // Material in pawns
+	minors       int   // Knights and bishops of both colors
+	bishopSquare []int // Square color (0 or 1) of every bishop
+	heavy        bool  // Whether any pawn, rook or queen is on the board
+}
+
+// parsePosition reads the piece placement field of a FEN string
+func parsePosition(fen string) (*position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid FEN %q", fen)
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("invalid FEN %q: expected 8 ranks", fen)
+	}
+
+	pos := &position{}
+	for rank, row := range ranks {
+		file := 0
+		for _, c := range row {
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+
+			piece := c | 0x20 // lower case
+			value, ok := pieceValues[piece]
+			if !ok && piece != 'k' {
+				return nil, fmt.Errorf("invalid FEN %q: unknown piece %q", fen, c)
+			}
+
+			if c == piece {
+				pos.black += value
+			} else {
+				pos.white += value
+			}
+
+			switch piece {
+			case 'b':
+				pos.bishopSquare = append(pos.bishopSquare, (rank+file)%2)
+				pos.minors++
+			case 'n':
+				pos.minors++
+			case 'p', 'r', 'q':
+				pos.heavy = true
+			}
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("invalid FEN %q: rank %d has %d files", fen, 8-rank, file)
+		}
+	}
+
+	return pos, nil
+}
+
+// insufficientMaterial reports whether neither side can win in variant. In
+// standard chess and Chess960 that is bare kings, a single minor piece, or
+// only bishops all on one square color. In three-check any piece can still
+// give the checks that win, so only bare kings are drawn, and in King of
+// the Hill a bare king can still win by reaching the center.
+func (p *position) insufficientMaterial(variant string) bool {
+	switch variant {
+	case model.VariantKingOfTheHill:
+		return false
+	case model.VariantThreeCheck:
+		return !p.heavy && p.minors == 0
+	}
+
+	if p.heavy {
+		return false
+	}
+	if p.minors <= 1 {
+		return true
+	}
+	if len(p.bishopSquare) != p.minors {
+		return false
+	}
+	for _, square := range p.bishopSquare[1:] {
+		if square != p.bishopSquare[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// adjudicate applies the adjudication rules after a move, returning the
+// result and end reason if the game should be ended. fen is the position
+// after the move and may be empty if the engine didn't send one, in which
+// case only the move cap applies. A FEN that can't be read is logged and
+// skips the material rules, so the move itself is still applied.
+func (s *Service) adjudicate(ctx context.Context, match *Match, fen string) (*GameResult, string) {
+	rules := s.adjudication
+
+	if rules.MoveCap > 0 && match.Plies >= 2*rules.MoveCap {
+		return &GameResult{
+			Winner:       model.ResultDraw,
+			Reason:       ReasonAdjudication,
+			Adjudication: AdjudicationMoveCap,
+		}, fmt.Sprintf("drawn by adjudication after %d moves", rules.MoveCap)
+	}
+
+	if fen == "" {
+		return nil, ""
+	}
+
+	pos, err := parsePosition(fen)
+	if err != nil {
+		logger := logging.FromContext(ctx)
+		logger.Warn().Err(err).Str("match_id", match.ID).Msg("Skipped material adjudication for unreadable position")
+		return nil, ""
+	}
+
+	if rules.InsufficientMaterial && pos.insufficientMaterial(match.Variant) {
+		return &GameResult{
+			Winner:       model.ResultDraw,
+			Reason:       ReasonAdjudication,
+			Adjudication: AdjudicationInsufficientMaterial,
+		}, "drawn by adjudication on insufficient material"
+	}
+
+	if rules.MaterialThreshold <= 0 || rules.MaterialPlies <= 0 {
+		return nil, ""
+	}
+
+	leader := ""
+	switch {
+	case pos.white-pos.black >= rules.MaterialThreshold:
+		leader = ColorWhite
+	case pos.black-pos.white >= rules.MaterialThreshold:
+		leader = ColorBlack
+	}
+
+	streak := &match.imbalance
+	if leader == "" || leader != streak.leader {
+		streak.leader = leader
+		streak.plies = 0
+	}
+	if leader == "" {
+		return nil, ""
+	}
+
+	streak.plies++
+	if streak.plies < rules.MaterialPlies {
+		return nil, ""
+	}
+
+	return &GameResult{
+		Winner:       leader,
+		Reason:       ReasonAdjudication,
+		Adjudication: AdjudicationMaterialImbalance,
+	}, fmt.Sprintf("%s won by adjudication on material for %d plies", leader, rules.MaterialPlies)
+}
@@ -0,0 +1,162 @@
+package matchmaking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReasonTimeout is the result reason recorded when a player's clock runs out
+const ReasonTimeout = "timeout"
+
+// maxTimeControlSeconds caps every time control field at one day
+const maxTimeControlSeconds = 24 * 60 * 60
+
+// TimeControl is the clock a game is played under. Either a base time with an
+// optional per-move increment, or a fixed time per move. The zero value is an
+// untimed game.
+type TimeControl struct {
+	BaseSeconds      int `json:"baseSeconds,omitempty"`
+	IncrementSeconds int `json:"incrementSeconds,omitempty"`
+	MoveSeconds      int `json:"moveSeconds,omitempty"` // Fixed time per move; excludes base and increment
+}
+
+// Validate checks that the time control is one of the supported shapes
+func (tc TimeControl) Validate() error {
+	if tc.BaseSeconds < 0 || tc.IncrementSeconds < 0 || tc.MoveSeconds < 0 {
+		return errors.New("time control values cannot be negative")
+	}
+	if tc.BaseSeconds > maxTimeControlSeconds || tc.IncrementSeconds > maxTimeControlSeconds || tc.MoveSeconds > maxTimeControlSeconds {
+		return errors.New("time control values cannot exceed one day")
+	}
+	if tc.MoveSeconds > 0 && (tc.BaseSeconds > 0 || tc.IncrementSeconds > 0) {
+		return errors.New("time per move cannot be combined with base time or increment")
+	}
+	if tc.BaseSeconds == 0 && tc.IncrementSeconds > 0 {
+		return errors.New("increment requires a base time")
+	}
+	return nil
+}
+
+// Untimed reports whether games under this time control have no clock
+func (tc TimeControl) Untimed() bool {
+	return tc.BaseSeconds == 0 && tc.MoveSeconds == 0
+}
+
+// Key identifies the queue for this time control, e.g. "300+5" or "move/10"
+func (tc TimeControl) Key() string {
+	switch {
+	case tc.Untimed():
+		return "untimed"
+	case tc.MoveSeconds > 0:
+		return fmt.Sprintf("move/%d", tc.MoveSeconds)
+	default:
+		return fmt.Sprintf("%d+%d", tc.BaseSeconds, tc.IncrementSeconds)
+	}
+}
+
+// Clock tracks both players' remaining time. For fixed time per move, the
+// remaining time is reset to the move time after every move.
+type Clock struct {
+	WhiteMillis   int64      `json:"whiteMillis"`
+	BlackMillis   int64      `json:"blackMillis"`
+	Turn          string     `json:"turn"`                    // Color whose clock is running
+	TurnStartedAt *time.Time `json:"turnStartedAt,omitempty"` // Nil until both players connect
+}
+
+// newClock returns a stopped clock for the time control, or nil if untimed
+func newClock(tc TimeControl) *Clock {
+	if tc.Untimed() {
+		return nil
+	}
+
+	allowance := tc.allowance()
+	return &Clock{
+		WhiteMillis: allowance.Milliseconds(),
+		BlackMillis: allowance.Milliseconds(),
+		Turn:        ColorWhite,
+	}
+}
+
+// allowance is the time each player starts with
+func (tc TimeControl) allowance() time.Duration {
+	if tc.MoveSeconds > 0 {
+		return time.Duration(tc.MoveSeconds) * time.Second
+	}
+	return time.Duration(tc.BaseSeconds) * time.Second
+}
+
+// start runs the clock of the side to move from now
+func (c *Clock) start(turn string, now time.Time) {
+	c.Turn = turn
+	c.TurnStartedAt = &now
+}
+
+// remaining returns the time left for a color
+func (c *Clock) remaining(color string) time.Duration {
+	if color == ColorWhite {
+		return time.Duration(c.WhiteMillis) * time.Millisecond
+	}
+	return time.Duration(c.BlackMillis) * time.Millisecond
+}
+
+// setRemaining stores the time left for a color
+func (c *Clock) setRemaining(color string, d time.Duration) {
+	if color == ColorWhite {
+		c.WhiteMillis = d.Milliseconds()
+	} else {
+		c.BlackMillis = d.Milliseconds()
+	}
+}
+
+// flagged reports whether the side to move has run out of time at now
+func (c *Clock) flagged(now time.Time) bool {
+	if c.TurnStartedAt == nil {
+		return false
+	}
+	return now.Sub(*c.TurnStartedAt) > c.remaining(c.Turn)
+}
+
+// applyMove stops the mover's clock at the time the server received the
+// move, applies the increment or per-move reset and starts the opponent's
+// clock. It reports whether the mover had already run out of time.
+func (c *Clock) applyMove(tc TimeControl, color string, at time.Time) (bool, error) {
+	if c.TurnStartedAt == nil {
+		return false, errors.New("clock has not started")
+	}
+	if color != c.Turn {
+		return false, fmt.Errorf("it is not %s's turn", color)
+	}
+
+	// Server instances sharing a match may disagree slightly on the time;
+	// never run backwards
+	elapsed := at.Sub(*c.TurnStartedAt)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	left := c.remaining(color) - elapsed
+	if left < 0 {
+		c.setRemaining(color, 0)
+		return true, nil
+	}
+
+	if tc.MoveSeconds > 0 {
+		left = tc.allowance()
+	} else {
+		left += time.Duration(tc.IncrementSeconds) * time.Second
+	}
+	c.setRemaining(color, left)
+
+	c.Turn = opposite(color)
+	c.TurnStartedAt = &at
+	return false, nil
+}
+
+// opposite returns the other color
+func opposite(color string) string {
+	if color == ColorWhite {
+		return ColorBlack
+	}
+	return ColorWhite
+}
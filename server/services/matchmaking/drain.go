@@ -0,0 +1,87 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+)
+
+// shutdownReason is sent to players taken off a queue by Drain
+const shutdownReason = "server is shutting down; join the queue again shortly"
+
+// Drain prepares the service for the server shutting down. Joins are
+// refused from then on. Queues that die with this server are emptied and
+// their players told why; shared queues are left for the other instances
+// to keep pairing. Every event stream on this instance is then ended so
+// clients reconnect elsewhere or once the server is back. Active matches
+// are left in the store, which keeps them for Restore.
+func (s *Service) Drain(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	s.draining.Store(true)
+
+	var errs []error
+	if !s.store.Shared() {
+		removed, err := s.emptyQueues(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		logger.Info().Int("players", removed).Msg("Removed queued players")
+	}
+
+	s.endStreams()
+	return errors.Join(errs...)
+}
+
+// emptyQueues takes every waiting player off their queue and notifies them,
+// returning how many were removed
+func (s *Service) emptyQueues(ctx context.Context) (int, error) {
+	keys, err := s.store.QueueKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	var errs []error
+	for _, key := range keys {
+		userIDs, err := s.store.QueuedPlayers(ctx, key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, userID := range userIDs {
+			if _, _, err := s.store.Dequeue(ctx, userID); err != nil {
+				// Paired or gone since the queue was read
+				if !errors.Is(err, ErrNotQueued) {
+					errs = append(errs, err)
+				}
+				continue
+			}
+
+			removed++
+			s.publish(ctx, userID, Event{
+				Type:          EventQueueClosed,
+				QueuePosition: -1,
+				Reason:        shutdownReason,
+			})
+		}
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// endStreams closes every subscriber channel on this instance, ending the
+// event streams reading from them
+func (s *Service) endStreams() {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for userID, channels := range s.subscribers {
+		for ch := range channels {
+			close(ch)
+		}
+		delete(s.subscribers, userID)
+	}
+}
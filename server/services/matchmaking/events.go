@@ -0,0 +1,165 @@
+package matchmaking
+
+import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+)
+
+// EventType identifies the kind of matchmaking event pushed to subscribers
+type EventType string
+
+const (
+	// EventQueuePosition is sent whenever a queued player's position changes
+	EventQueuePosition EventType = "queue_position"
+	// EventMatchFound is sent to both players once a game has been created
+	EventMatchFound EventType = "match_found"
+	// EventMatchCompleted is sent to both players when their game has a result
+	EventMatchCompleted EventType = "match_completed"
+	// EventMatchCancelled is sent to both players when their match is torn down
+	// without a result
+	EventMatchCancelled EventType = "match_cancelled"
+	// EventQueueClosed is sent to queued players taken off their queue
+	// because the server is shutting down
+	EventQueueClosed EventType = "queue_closed"
+)
+
+// subscriberBufferSize is how many events a subscriber can lag behind before
+// further events are dropped for it
+const subscriberBufferSize = 16
+
+// relayRetryDelay is how long the event relay waits before listening again
+// after losing its connection to the store
+const relayRetryDelay = time.Second
+
+// Event is a matchmaking update for a single player
+type Event struct {
+	Type          EventType `json:"type"`
+	QueuePosition int       `json:"queuePosition"`
+	Match         *Match    `json:"match,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// Subscribe registers a listener for events concerning userID. The returned
+// function must be called to release the subscription.
+func (s *Service) Subscribe(userID int) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+
+	s.subMu.Lock()
+	// Streams end straight away once the service is draining
+	if s.draining.Load() {
+		s.subMu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	s.subscribers[userID][ch] = struct{}{}
+	s.subMu.Unlock()
+
+	unsubscribe := func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+
+		// Already released, or ended by Drain
+		if _, ok := s.subscribers[userID][ch]; !ok {
+			return
+		}
+
+		delete(s.subscribers[userID], ch)
+		if len(s.subscribers[userID]) == 0 {
+			delete(s.subscribers, userID)
+		}
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// StartEventRelay passes events published through the store, by this or
+// any other server instance, to this instance's subscribers until ctx is
+// cancelled. It blocks, so callers should run it in a goroutine.
+func (s *Service) StartEventRelay(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	for {
+		err := s.store.ListenEvents(ctx, s.deliver)
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Error().Err(err).Msg("Matchmaking event relay stopped, restarting")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(relayRetryDelay):
+		}
+	}
+}
+
+// deliver hands an event to every subscriber of userID on this instance
+// without blocking. Events are dropped for subscribers whose buffer is full.
+func (s *Service) deliver(userID int, event Event) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for ch := range s.subscribers[userID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publish sends an event to userID's subscribers on every instance. Events
+// are best effort, so failures are only logged.
+func (s *Service) publish(ctx context.Context, userID int, event Event) {
+	if err := s.store.PublishEvent(ctx, userID, event); err != nil {
+		logger := logging.FromContext(ctx)
+		logger.Warn().Err(err).Int("user_id", userID).Str("event", string(event.Type)).Msg("Failed to publish matchmaking event")
+	}
+}
+
+// publishQueuePositions notifies every player in a queue from index start
+// onwards of their current position
+func (s *Service) publishQueuePositions(ctx context.Context, key string, start int) {
+	userIDs, err := s.store.QueuedPlayers(ctx, key)
+	if err != nil {
+		logger := logging.FromContext(ctx)
+		logger.Warn().Err(err).Str("queue", key).Msg("Failed to read queue for position updates")
+		return
+	}
+
+	for i := max(start, 0); i < len(userIDs); i++ {
+		s.publish(ctx, userIDs[i], Event{
+			Type:          EventQueuePosition,
+			QueuePosition: i,
+		})
+	}
+}
+
+// snapshot copies a match so the copy can be handed out without exposing
+// later mutations
+func (m *Match) snapshot() *Match {
+	snapshot := *m
+	if m.Clock != nil {
+		clock := *m.Clock
+		snapshot.Clock = &clock
+	}
+	return &snapshot
+}
+
+// publishMatch sends the same match event to both players of a match. The
+// match is copied so subscribers never observe later mutations.
+func (s *Service) publishMatch(ctx context.Context, eventType EventType, match *Match, reason string) {
+	event := Event{
+		Type:          eventType,
+		QueuePosition: -1,
+		Match:         match.snapshot(),
+		Reason:        reason,
+	}
+	s.publish(ctx, match.Player1.UserID, event)
+	s.publish(ctx, match.Player2.UserID, event)
+}
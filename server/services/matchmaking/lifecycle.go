@@ -0,0 +1,384 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// Match statuses. A match starts as matched, becomes in_progress once both
+// players have connected to the engine and ends in one of the terminal
+// statuses, at which point it is released so both players can queue again.
+const (
+	StatusMatched    = "matched"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusError      = "error"
+	StatusExpired    = "expired"
+	StatusCancelled  = "cancelled"
+)
+
+// allowedTransitions lists the statuses each non-terminal status may move to
+var allowedTransitions = map[string][]string{
+	StatusMatched:    {StatusInProgress, StatusCompleted, StatusError, StatusExpired, StatusCancelled},
+	StatusInProgress: {StatusCompleted, StatusError, StatusExpired, StatusCancelled},
+}
+
+// LifecycleConfig holds the deadlines enforced by the match reaper
+type LifecycleConfig struct {
+	ConnectTimeout time.Duration // How long a match may wait for both players to connect
+	GameTimeout    time.Duration // How long an untimed game may stay in progress
+	ReapInterval   time.Duration // How often the reaper checks deadlines and clocks
+	ReconnectGrace time.Duration // How long a disconnected player has to return
+}
+
+// GameEventType identifies an event reported about an engine game
+type GameEventType string
+
+const (
+	// GameEventPlayerConnected is reported when a player joins the engine game
+	GameEventPlayerConnected GameEventType = "player_connected"
+	// GameEventPlayerDisconnected is reported when a player's connection drops
+	GameEventPlayerDisconnected GameEventType = "player_disconnected"
+	// GameEventMove is reported when a player's move has been accepted
+	GameEventMove GameEventType = "move"
+	// GameEventGameOver is reported when the engine declares a result
+	GameEventGameOver GameEventType = "game_over"
+	// GameEventError is reported when the engine can no longer run the game
+	GameEventError GameEventType = "error"
+)
+
+// GameEvent is an engine-side occurrence that drives a match's status
+type GameEvent struct {
+	Type    GameEventType `json:"type" binding:"required"`
+	UserID  int           `json:"userId,omitempty"`
+	Result  *GameResult   `json:"result,omitempty"`
+	Message string        `json:"message,omitempty"`
+	FEN     string        `json:"fen,omitempty"` // Position after a move, used for adjudication
+}
+
+// GameResult is the outcome of a game as reported by the engine
+type GameResult struct {
+	Winner       string `json:"winner"` // "white", "black" or "draw"
+	Reason       string `json:"reason"`
+	Adjudication string `json:"adjudication,omitempty"` // Rule that decided an adjudicated game
+}
+
+// decidedByServer reports whether the result was reached by the server
+// rather than the engine
+func (r *GameResult) decidedByServer() bool {
+	return r.Reason == ReasonTimeout || r.Reason == ReasonAdjudication
+}
+
+// isTerminal reports whether a match in this status is finished
+func isTerminal(status string) bool {
+	_, hasTransitions := allowedTransitions[status]
+	return !hasTransitions
+}
+
+// canTransition reports whether a match may move from one status to another
+func canTransition(from, to string) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// errMatchUnchanged is returned by a match update that leaves the match as
+// it was, so the store skips the write
+var errMatchUnchanged = errors.New("match unchanged")
+
+// transition moves a match to a new status. Terminal statuses record the
+// reason; the store then releases the match.
+func (m *Match) transition(to, reason string) error {
+	if !canTransition(m.Status, to) {
+		return fmt.Errorf("invalid match transition from %s to %s", m.Status, to)
+	}
+
+	now := time.Now()
+	m.Status = to
+	m.UpdatedAt = now
+
+	if !isTerminal(to) {
+		return nil
+	}
+
+	m.EndReason = reason
+	m.EndedAt = &now
+
+	return nil
+}
+
+// updateMatch applies update to a match in the store and notifies both
+// players if the update ended it. update may run more than once if another
+// server changes the match at the same time, so it must only change the
+// match it is given.
+func (s *Service) updateMatch(ctx context.Context, matchID string, update func(*Match) error) (*Match, error) {
+	match, err := s.store.UpdateMatch(ctx, matchID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTerminal(match.Status) {
+		eventType := EventMatchCancelled
+		if match.Status == StatusCompleted {
+			eventType = EventMatchCompleted
+		}
+		s.publishMatch(ctx, eventType, match, match.EndReason)
+	}
+
+	return match, nil
+}
+
+// HandleGameEvent applies an engine event to the match hosting gameID and
+// records the result if the event finished the game
+func (s *Service) HandleGameEvent(ctx context.Context, gameID string, event GameEvent) error {
+	matchID, err := s.store.MatchIDForGame(ctx, gameID)
+	if err != nil {
+		return err
+	}
+
+	var finished bool
+	match, err := s.updateMatch(ctx, matchID, func(match *Match) error {
+		var err error
+		finished, err = s.applyGameEvent(ctx, match, event)
+		return err
+	})
+	if err != nil || !finished {
+		return err
+	}
+
+	if err := s.recordGame(ctx, match); err != nil {
+		return err
+	}
+
+	// The engine doesn't know about results decided by the server, so the
+	// game it is still running has to be stopped
+	if match.Result.decidedByServer() {
+		if err := s.engineService.DeleteGame(ctx, match.Engine, match.GameID); err != nil {
+			return fmt.Errorf("failed to delete game %s from engine: %w", match.GameID, err)
+		}
+	}
+
+	return nil
+}
+
+// applyGameEvent updates a match for an event, reporting whether the event
+// completed it
+func (s *Service) applyGameEvent(ctx context.Context, match *Match, event GameEvent) (bool, error) {
+	switch event.Type {
+	case GameEventPlayerConnected:
+		player := match.player(event.UserID)
+		if player == nil {
+			return false, errors.New("player is not in this match")
+		}
+		player.Connected = true
+		player.ReconnectDeadline = nil
+
+		if match.Status == StatusMatched && match.Player1.Connected && match.Player2.Connected {
+			if err := match.transition(StatusInProgress, ""); err != nil {
+				return false, err
+			}
+			if match.Clock != nil {
+				match.Clock.start(match.sideToMove(), match.UpdatedAt)
+			}
+		}
+		return false, nil
+
+	case GameEventMove:
+		color := match.colorOf(event.UserID)
+		if color == "" {
+			return false, errors.New("player is not in this match")
+		}
+
+		// Clocks run on the server's time, like the reaper that flags them
+		if match.Clock != nil {
+			flagged, err := match.Clock.applyMove(match.TimeControl, color, time.Now())
+			if err != nil {
+				return false, err
+			}
+			if flagged {
+				match.Result = &GameResult{Winner: opposite(color), Reason: ReasonTimeout}
+				if err := match.transition(StatusCompleted, color+" ran out of time"); err != nil {
+					return false, err
+				}
+				return true, nil
+			}
+		}
+
+		match.Plies++
+		result, reason := s.adjudicate(ctx, match, event.FEN)
+		if result == nil {
+			return false, nil
+		}
+
+		match.Result = result
+		if err := match.transition(StatusCompleted, reason); err != nil {
+			return false, err
+		}
+		return true, nil
+
+	case GameEventPlayerDisconnected:
+		return false, s.markDisconnected(match, event.UserID, time.Now())
+
+	case GameEventGameOver:
+		if event.Result == nil {
+			return false, errors.New("game over event requires a result")
+		}
+		match.Result = event.Result
+		if err := match.transition(StatusCompleted, event.Result.Reason); err != nil {
+			return false, err
+		}
+		return true, nil
+
+	case GameEventError:
+		return false, match.transition(StatusError, event.Message)
+
+	default:
+		return false, fmt.Errorf("unknown game event type: %s", event.Type)
+	}
+}
+
+// recordGame stores the result of a completed match
+func (s *Service) recordGame(ctx context.Context, match *Match) error {
+	whiteModelID, blackModelID := match.Player1.ModelID, match.Player2.ModelID
+	if match.WhitePlayer != match.Player1.UserID {
+		whiteModelID, blackModelID = blackModelID, whiteModelID
+	}
+
+	var openingName, startFEN string
+	if match.Opening != nil {
+		openingName, startFEN = match.Opening.Name, match.Opening.FEN
+	}
+
+	_, err := s.recorder.RecordGame(ctx, &model.Game{
+		MatchID:      match.ID,
+		GameID:       match.GameID,
+		WhiteUserID:  match.WhitePlayer,
+		BlackUserID:  match.BlackPlayer,
+		WhiteModelID: whiteModelID,
+		BlackModelID: blackModelID,
+		Result:       match.Result.Winner,
+		Reason:       match.Result.Reason,
+		Adjudication: match.Result.Adjudication,
+		Opening:      openingName,
+		StartFEN:     startFEN,
+		Variant:      match.Variant,
+		StartedAt:    match.CreatedAt,
+		EndedAt:      *match.EndedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record game %s: %w", match.GameID, err)
+	}
+
+	return nil
+}
+
+// StartReaper expires stuck matches, forfeits abandoned ones and declares
+// flag losses until ctx is cancelled. It blocks, so callers should run it in a goroutine.
+func (s *Service) StartReaper(ctx context.Context) {
+	ticker := time.NewTicker(s.lifecycle.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.reap(ctx, now)
+		}
+	}
+}
+
+// reap ends every match that has outlived the deadline for its status, whose
+// disconnected players did not return in time or where the side to move has
+// run out of time, then records the decided results
+// and releases the engine games the matches were holding. Every server
+// instance reaps, but the store lets only one of them end each match.
+func (s *Service) reap(ctx context.Context, now time.Time) {
+	logger := logging.FromContext(ctx)
+
+	matches, err := s.store.ListMatches(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to list matches to reap")
+		return
+	}
+
+	for _, candidate := range matches {
+		match, err := s.updateMatch(ctx, candidate.ID, func(match *Match) error {
+			status, reason, result := s.reapOutcome(match, now)
+			if status == "" {
+				return errMatchUnchanged
+			}
+
+			match.Result = result
+			return match.transition(status, reason)
+		})
+		switch {
+		case errors.Is(err, errMatchUnchanged), errors.Is(err, ErrMatchNotFound):
+			continue
+		case err != nil:
+			logger.Error().Err(err).Str("match_id", candidate.ID).Msg("Failed to reap match")
+			continue
+		}
+
+		logger.Info().
+			Str("match_id", match.ID).
+			Str("game_id", match.GameID).
+			Str("status", match.Status).
+			Str("reason", match.EndReason).
+			Msg("Reaped match")
+
+		if match.Status == StatusCompleted {
+			if err := s.recordGame(ctx, match); err != nil {
+				logger.Error().Err(err).Str("match_id", match.ID).Msg("Failed to record reaped game")
+			}
+		}
+
+		if err := s.engineService.DeleteGame(ctx, match.Engine, match.GameID); err != nil {
+			logger.Warn().Err(err).Str("game_id", match.GameID).Msg("Failed to delete reaped game from engine")
+		}
+	}
+}
+
+// reapOutcome decides whether the reaper should end a match, returning the
+// status to move it to, the reason and, for forfeits, the result. An empty
+// status leaves the match alone.
+func (s *Service) reapOutcome(match *Match, now time.Time) (string, string, *GameResult) {
+	whiteGone := match.player(match.WhitePlayer).abandoned(now)
+	blackGone := match.player(match.BlackPlayer).abandoned(now)
+
+	switch {
+	case whiteGone && blackGone:
+		return StatusCancelled, "both players disconnected", nil
+	case whiteGone:
+		return StatusCompleted, "white player did not reconnect", &GameResult{Winner: model.ResultBlack, Reason: ReasonAbandonment}
+	case blackGone:
+		return StatusCompleted, "black player did not reconnect", &GameResult{Winner: model.ResultWhite, Reason: ReasonAbandonment}
+	}
+
+	if match.Clock != nil && match.Clock.flagged(now) {
+		color := match.Clock.Turn
+		return StatusCompleted, color + " ran out of time", &GameResult{Winner: opposite(color), Reason: ReasonTimeout}
+	}
+
+	switch match.Status {
+	case StatusMatched:
+		if now.Sub(match.UpdatedAt) > s.lifecycle.ConnectTimeout {
+			return StatusExpired, fmt.Sprintf("players did not connect within %s", s.lifecycle.ConnectTimeout), nil
+		}
+	case StatusInProgress:
+		// Timed games end by their clocks, which may allow far longer
+		if match.Clock == nil && now.Sub(match.UpdatedAt) > s.lifecycle.GameTimeout {
+			return StatusExpired, fmt.Sprintf("game did not finish within %s", s.lifecycle.GameTimeout), nil
+		}
+	}
+
+	return "", "", nil
+}
@@ -0,0 +1,441 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// MemoryStore keeps matchmaking state in process. It suits a single server
+// instance. Queues are lost on restart; active matches are too unless they
+// are saved through a persister.
+type MemoryStore struct {
+	persister MatchPersisterInterface // Nil if matches aren't saved
+
+	mu            sync.Mutex
+	queues        map[string][]Player // Players waiting to be matched, by queue key
+	queued        map[int]string      // Queue key of every queued player, including those being paired
+	matches       map[string]*Match   // Active matches by match ID
+	playerMatches map[int]string      // Match ID by player ID
+	gameMatches   map[string]string   // Match ID by engine game ID
+	rotations     map[string]OpeningRotation
+	saves         map[string]*matchSaves // Ordering of each active match's saves
+
+	listenMu  sync.Mutex
+	listeners map[*func(int, Event)]struct{}
+}
+
+// NewMemoryStore creates an empty in-memory matchmaking store. Active
+// matches are saved through persister, if not nil, so Restore can reload
+// them after a restart.
+func NewMemoryStore(persister MatchPersisterInterface) StoreInterface {
+	return &MemoryStore{
+		persister:     persister,
+		queues:        make(map[string][]Player),
+		queued:        make(map[int]string),
+		matches:       make(map[string]*Match),
+		playerMatches: make(map[int]string),
+		gameMatches:   make(map[string]string),
+		rotations:     make(map[string]OpeningRotation),
+		saves:         make(map[string]*matchSaves),
+		listeners:     make(map[*func(int, Event)]struct{}),
+	}
+}
+
+// Enqueue adds a player to a queue and takes the first two off it once it
+// holds a pair
+func (m *MemoryStore) Enqueue(_ context.Context, key string, player Player) (int, []Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.playerMatches[player.UserID]; ok {
+		return 0, nil, ErrAlreadyInMatch
+	}
+	if _, ok := m.queued[player.UserID]; ok {
+		return 0, nil, ErrAlreadyQueued
+	}
+
+	m.queued[player.UserID] = key
+	m.queues[key] = append(m.queues[key], player)
+	position := len(m.queues[key]) - 1
+
+	queue := m.queues[key]
+	if len(queue) < 2 {
+		return position, nil, nil
+	}
+
+	pair := []Player{queue[0], queue[1]}
+	m.setQueue(key, queue[2:])
+	return position, pair, nil
+}
+
+// Requeue puts players back at the front of a queue
+func (m *MemoryStore) Requeue(_ context.Context, key string, players []Player) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.setQueue(key, append(slices.Clone(players), m.queues[key]...))
+	for _, player := range players {
+		m.queued[player.UserID] = key
+	}
+	return nil
+}
+
+// Dequeue removes a waiting player from their queue
+func (m *MemoryStore) Dequeue(_ context.Context, userID int) (string, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, position, ok := m.find(userID)
+	if !ok {
+		return "", -1, ErrNotQueued
+	}
+
+	queue := m.queues[key]
+	m.setQueue(key, append(queue[:position:position], queue[position+1:]...))
+	delete(m.queued, userID)
+	return key, position, nil
+}
+
+// QueuePosition returns the key and position of a queued player
+func (m *MemoryStore) QueuePosition(_ context.Context, userID int) (string, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.queued[userID]
+	if !ok {
+		return "", -1, ErrNotQueued
+	}
+	if _, position, found := m.find(userID); found {
+		return key, position, nil
+	}
+	// Taken off the queue to be paired
+	return key, 0, nil
+}
+
+// QueuedPlayers returns the user IDs waiting in a queue
+func (m *MemoryStore) QueuedPlayers(_ context.Context, key string) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userIDs := make([]int, 0, len(m.queues[key]))
+	for _, player := range m.queues[key] {
+		userIDs = append(userIDs, player.UserID)
+	}
+	return userIDs, nil
+}
+
+// QueueKeys returns the keys of the queues holding waiting players
+func (m *MemoryStore) QueueKeys(_ context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.queues))
+	for key := range m.queues {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Shared reports false: the queues live and die with this process
+func (m *MemoryStore) Shared() bool {
+	return false
+}
+
+// CountQueued returns the number of queued players
+func (m *MemoryStore) CountQueued(_ context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.queued), nil
+}
+
+// CreateMatch stores a new match and its player and game lookups
+func (m *MemoryStore) CreateMatch(ctx context.Context, match *Match) error {
+	stored := match.snapshot()
+
+	m.mu.Lock()
+	m.matches[match.ID] = stored
+	for _, userID := range []int{match.Player1.UserID, match.Player2.UserID} {
+		delete(m.queued, userID)
+		m.playerMatches[userID] = match.ID
+	}
+	m.gameMatches[match.GameID] = match.ID
+	saves, seq := m.nextSave(match.ID, false)
+	m.mu.Unlock()
+
+	m.persist(ctx, saves, seq, stored)
+	return nil
+}
+
+// UpdateMatch applies update to a copy of a match and keeps the copy. The
+// copy is saved through the persister after the lock is released, so a
+// slow save only holds up later saves of the same match.
+func (m *MemoryStore) UpdateMatch(ctx context.Context, matchID string, update func(*Match) error) (*Match, error) {
+	m.mu.Lock()
+
+	current, ok := m.matches[matchID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrMatchNotFound
+	}
+
+	match := current.snapshot()
+	if err := update(match); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	ended := isTerminal(match.Status)
+	if ended {
+		delete(m.matches, matchID)
+		delete(m.playerMatches, match.Player1.UserID)
+		delete(m.playerMatches, match.Player2.UserID)
+		delete(m.gameMatches, match.GameID)
+	} else {
+		m.matches[matchID] = match
+	}
+	saves, seq := m.nextSave(matchID, ended)
+	m.mu.Unlock()
+
+	m.persist(ctx, saves, seq, match)
+	return match.snapshot(), nil
+}
+
+// GetMatch returns a copy of an active match
+func (m *MemoryStore) GetMatch(_ context.Context, matchID string) (*Match, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	match, ok := m.matches[matchID]
+	if !ok {
+		return nil, ErrMatchNotFound
+	}
+	return match.snapshot(), nil
+}
+
+// MatchIDForPlayer returns the ID of a player's active match
+func (m *MemoryStore) MatchIDForPlayer(_ context.Context, userID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matchID, ok := m.playerMatches[userID]
+	if !ok {
+		return "", ErrMatchNotFound
+	}
+	return matchID, nil
+}
+
+// MatchIDForGame returns the ID of the match hosting an engine game
+func (m *MemoryStore) MatchIDForGame(_ context.Context, gameID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matchID, ok := m.gameMatches[gameID]
+	if !ok {
+		return "", ErrMatchNotFound
+	}
+	return matchID, nil
+}
+
+// ListMatches returns copies of every active match
+func (m *MemoryStore) ListMatches(_ context.Context) ([]*Match, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := make([]*Match, 0, len(m.matches))
+	for _, match := range m.matches {
+		matches = append(matches, match.snapshot())
+	}
+	return matches, nil
+}
+
+// CountMatches returns the number of active matches
+func (m *MemoryStore) CountMatches(_ context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.matches), nil
+}
+
+// OpeningRotation returns a pair's progress through an opening suite
+func (m *MemoryStore) OpeningRotation(_ context.Context, key string) (OpeningRotation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rotations[key], nil
+}
+
+// SaveOpeningRotation records a pair's progress through an opening suite
+func (m *MemoryStore) SaveOpeningRotation(_ context.Context, key string, rotation OpeningRotation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rotations[key] = rotation
+	return nil
+}
+
+// PublishEvent hands an event to every listener before returning, so
+// events reach subscribers in the order they were published
+func (m *MemoryStore) PublishEvent(_ context.Context, userID int, event Event) error {
+	m.listenMu.Lock()
+	defer m.listenMu.Unlock()
+
+	for deliver := range m.listeners {
+		(*deliver)(userID, event)
+	}
+	return nil
+}
+
+// ListenEvents registers deliver for published events until ctx is
+// cancelled
+func (m *MemoryStore) ListenEvents(ctx context.Context, deliver func(userID int, event Event)) error {
+	m.listenMu.Lock()
+	m.listeners[&deliver] = struct{}{}
+	m.listenMu.Unlock()
+
+	<-ctx.Done()
+
+	m.listenMu.Lock()
+	delete(m.listeners, &deliver)
+	m.listenMu.Unlock()
+	return nil
+}
+
+// Restore reloads the matches saved through the persister. A match sharing
+// a player with a match restored before it is dropped; matches are
+// restored in ID order, so the same one is kept every time.
+func (m *MemoryStore) Restore(ctx context.Context) ([]string, error) {
+	if m.persister == nil {
+		return nil, nil
+	}
+
+	saved, err := m.persister.ListActiveMatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var restored []string
+	for _, row := range saved {
+		match, err := decodeMatch(row.Data)
+		if err != nil {
+			return nil, fmt.Errorf("active match %s: %w", row.ID, err)
+		}
+
+		_, taken1 := m.playerMatches[match.Player1.UserID]
+		_, taken2 := m.playerMatches[match.Player2.UserID]
+		if taken1 || taken2 || isTerminal(match.Status) {
+			if err := m.persister.DeleteActiveMatch(ctx, match.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		m.matches[match.ID] = match
+		m.playerMatches[match.Player1.UserID] = match.ID
+		m.playerMatches[match.Player2.UserID] = match.ID
+		m.gameMatches[match.GameID] = match.ID
+		restored = append(restored, match.ID)
+	}
+
+	return restored, nil
+}
+
+// matchSaves orders the saves of one match. Versions are numbered under
+// the store's lock but written after it is released, so a version is
+// skipped if a later one has already been written.
+type matchSaves struct {
+	taken uint64 // Last version numbered; guarded by the store's mu
+
+	mu    sync.Mutex
+	saved uint64 // Last version written
+}
+
+// nextSave numbers a new version of a match for persist, forgetting the
+// match's save ordering once it has ended. Callers must hold m.mu.
+func (m *MemoryStore) nextSave(matchID string, ended bool) (*matchSaves, uint64) {
+	if m.persister == nil {
+		return nil, 0
+	}
+
+	saves, ok := m.saves[matchID]
+	if !ok {
+		saves = &matchSaves{}
+		m.saves[matchID] = saves
+	}
+	if ended {
+		delete(m.saves, matchID)
+	}
+
+	saves.taken++
+	return saves, saves.taken
+}
+
+// persist saves version seq of a match through the persister, or deletes
+// the match once it has ended. Callers must not hold m.mu. A failed save is
+// logged rather than returned, as the match has already changed in memory;
+// its next save writes the whole match again.
+func (m *MemoryStore) persist(ctx context.Context, saves *matchSaves, seq uint64, match *Match) {
+	if saves == nil {
+		return
+	}
+
+	saves.mu.Lock()
+	defer saves.mu.Unlock()
+
+	if seq <= saves.saved {
+		return
+	}
+
+	var err error
+	if isTerminal(match.Status) {
+		err = m.persister.DeleteActiveMatch(ctx, match.ID)
+	} else {
+		var data []byte
+		if data, err = encodeMatch(match); err == nil {
+			err = m.persister.SaveActiveMatch(ctx, &model.ActiveMatch{
+				ID:     match.ID,
+				GameID: match.GameID,
+				Data:   data,
+			})
+		}
+	}
+	if err != nil {
+		logger := logging.FromContext(ctx)
+		logger.Warn().Err(err).Str("match_id", match.ID).Msg("Failed to save active match")
+		return
+	}
+
+	saves.saved = seq
+}
+
+// find returns the queue key and position of a player waiting in a queue.
+// Callers must hold m.mu.
+func (m *MemoryStore) find(userID int) (string, int, bool) {
+	key, ok := m.queued[userID]
+	if !ok {
+		return "", -1, false
+	}
+	for i, player := range m.queues[key] {
+		if player.UserID == userID {
+			return key, i, true
+		}
+	}
+	return "", -1, false
+}
+
+// setQueue replaces a queue, dropping it once empty. Callers must hold m.mu.
+func (m *MemoryStore) setQueue(key string, queue []Player) {
+	if len(queue) == 0 {
+		delete(m.queues, key)
+		return
+	}
+	m.queues[key] = queue
+}
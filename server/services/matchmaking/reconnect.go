@@ -0,0 +1,82 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ReasonAbandonment is the result reason recorded when a disconnected player
+// does not return within the reconnect grace period
+const ReasonAbandonment = "abandonment"
+
+// MarkPlayerDisconnected holds the player's match open for the reconnect
+// grace period instead of ending it. If the player does not reconnect in
+// time, the reaper forfeits the game.
+func (s *Service) MarkPlayerDisconnected(ctx context.Context, userID int) error {
+	matchID, err := s.store.MatchIDForPlayer(ctx, userID)
+	if errors.Is(err, ErrMatchNotFound) {
+		return errors.New("player is not in any match")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.updateMatch(ctx, matchID, func(match *Match) error {
+		return s.markDisconnected(match, userID, time.Now())
+	})
+	if errors.Is(err, ErrMatchNotFound) {
+		// The match ended in the meantime
+		return nil
+	}
+	return err
+}
+
+// markDisconnected starts the reconnect grace period for a player
+func (s *Service) markDisconnected(match *Match, userID int, now time.Time) error {
+	player := match.player(userID)
+	if player == nil {
+		return errors.New("player is not in this match")
+	}
+
+	// Keep the original deadline if the engine and gateway both report the drop
+	if player.ReconnectDeadline != nil {
+		return nil
+	}
+
+	deadline := now.Add(s.lifecycle.ReconnectGrace)
+	player.Connected = false
+	player.ReconnectDeadline = &deadline
+
+	return nil
+}
+
+// abandoned reports whether a disconnected player's grace period has passed
+func (p *Player) abandoned(now time.Time) bool {
+	return p != nil && p.ReconnectDeadline != nil && now.After(*p.ReconnectDeadline)
+}
+
+// player returns the match's player with the given user ID, or nil
+func (m *Match) player(userID int) *Player {
+	switch userID {
+	case m.Player1.UserID:
+		return &m.Player1
+	case m.Player2.UserID:
+		return &m.Player2
+	default:
+		return nil
+	}
+}
+
+// colorOf returns the color a user plays in the match, or "" if they are not
+// one of its players
+func (m *Match) colorOf(userID int) string {
+	switch userID {
+	case m.WhitePlayer:
+		return ColorWhite
+	case m.BlackPlayer:
+		return ColorBlack
+	default:
+		return ""
+	}
+}
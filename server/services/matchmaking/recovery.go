@@ -0,0 +1,154 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+)
+
+// orphanGrace is how long an engine game without a match is given before
+// it is deleted, so games another server is still creating a match for
+// are left alone
+const orphanGrace = 5 * time.Second
+
+// Restore reloads the active matches kept from before a restart, then
+// reconciles them with the games live on the engines. The players' gateway
+// connections did not survive the restart, so each restored player gets the
+// reconnect grace period to come back.
+func (s *Service) Restore(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	restored, err := s.store.Restore(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info().Int("matches", len(restored)).Msg("Restored active matches")
+
+	now := time.Now()
+	for _, matchID := range restored {
+		_, err := s.updateMatch(ctx, matchID, func(match *Match) error {
+			if err := s.markDisconnected(match, match.Player1.UserID, now); err != nil {
+				return err
+			}
+			return s.markDisconnected(match, match.Player2.UserID, now)
+		})
+		if err != nil && !errors.Is(err, ErrMatchNotFound) {
+			logger.Error().Err(err).Str("match_id", matchID).Msg("Failed to start the reconnect grace period for a restored match")
+		}
+	}
+
+	s.reconcile(ctx)
+	return nil
+}
+
+// reconcile compares the active matches with the games each engine reports.
+// Matches whose game no longer exists end with an error. Engines that can't
+// be reached are skipped, leaving their matches to the reaper's deadlines.
+func (s *Service) reconcile(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	// Games are listed before matches, so a match created in between is
+	// never mistaken for one whose game has gone
+	live := s.liveGames(ctx)
+
+	matches, err := s.store.ListMatches(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to list matches to reconcile")
+		return
+	}
+	slices.SortFunc(matches, func(a, b *Match) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	for _, match := range matches {
+		games, listed := live[match.Engine]
+		if !listed || games[match.GameID] {
+			continue
+		}
+
+		_, err := s.updateMatch(ctx, match.ID, func(match *Match) error {
+			return match.transition(StatusError, "game no longer exists on the engine")
+		})
+		if errors.Is(err, ErrMatchNotFound) {
+			continue
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("match_id", match.ID).Msg("Failed to end match with a missing game")
+			continue
+		}
+		logger.Warn().Str("match_id", match.ID).Str("game_id", match.GameID).Msg("Ended match whose game no longer exists on the engine")
+	}
+}
+
+// liveGames returns the IDs of the games live on each engine that can be
+// reached, by engine URL
+func (s *Service) liveGames(ctx context.Context) map[string]map[string]bool {
+	logger := logging.FromContext(ctx)
+
+	live := make(map[string]map[string]bool)
+	for _, instance := range s.engineService.Instances() {
+		games, err := s.engineService.ListGames(ctx, instance.URL)
+		if err != nil {
+			logger.Warn().Err(err).Str("engine", instance.URL).Msg("Failed to list engine games, skipping the engine")
+			continue
+		}
+
+		live[instance.URL] = make(map[string]bool, len(games))
+		for _, gameID := range games {
+			live[instance.URL][gameID] = true
+		}
+	}
+	return live
+}
+
+// DeleteOrphanGames deletes the engine games that no match refers to,
+// checking again after orphanGrace. It runs once after Restore, in the
+// background so startup doesn't wait out the grace period.
+func (s *Service) DeleteOrphanGames(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	type game struct{ engine, id string }
+	var orphans []game
+	for engineURL, games := range s.liveGames(ctx) {
+		for gameID := range games {
+			if s.orphaned(ctx, gameID) {
+				orphans = append(orphans, game{engineURL, gameID})
+			}
+		}
+	}
+	if len(orphans) == 0 {
+		return
+	}
+	slices.SortFunc(orphans, func(a, b game) int {
+		if a.engine != b.engine {
+			return strings.Compare(a.engine, b.engine)
+		}
+		return strings.Compare(a.id, b.id)
+	})
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(orphanGrace):
+	}
+
+	for _, orphan := range orphans {
+		if !s.orphaned(ctx, orphan.id) {
+			continue
+		}
+		if err := s.engineService.DeleteGame(ctx, orphan.engine, orphan.id); err != nil {
+			logger.Warn().Err(err).Str("engine", orphan.engine).Str("game_id", orphan.id).Msg("Failed to delete orphaned engine game")
+			continue
+		}
+		logger.Info().Str("engine", orphan.engine).Str("game_id", orphan.id).Msg("Deleted orphaned engine game")
+	}
+}
+
+// orphaned reports whether no active match refers to an engine game. Games
+// are kept if the store can't tell.
+func (s *Service) orphaned(ctx context.Context, gameID string) bool {
+	_, err := s.store.MatchIDForGame(ctx, gameID)
+	return errors.Is(err, ErrMatchNotFound)
+}
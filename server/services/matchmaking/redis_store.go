@@ -0,0 +1,650 @@
+package matchmaking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// pairingLease is how long players taken off a queue for pairing stay
+// reserved. If the server pairing them dies before creating the match or
+// requeueing them, they may queue again once it runs out.
+const pairingLease = 2 * time.Minute
+
+// maxUpdateAttempts bounds how often a match update is retried when other
+// servers keep changing the match underneath it
+const maxUpdateAttempts = 10
+
+// Each entry in the queued hash is "<lease deadline>|<queue key>", with the
+// deadline in Unix milliseconds. Players waiting in a queue have a deadline
+// of 0. Deadlines are digits, so the first separator splits the entry.
+const queuedSeparator = "|"
+
+// enqueueScript adds a player to a queue and pops the first two players
+// once the queue holds a pair, all in one step so concurrent joins on
+// different servers can't pair a player twice. LPOP with a count needs
+// Redis 6.2 or later.
+//
+// KEYS: queue list, queued hash, players hash, player match hash
+// ARGV: user ID, queue key, player JSON, pairing lease in milliseconds
+// Returns {"matched"} or {"queued"} if the player can't join, {"ok",
+// position} if they are waiting, and {"ok", position, lease, paired player
+// JSON...} once a pair was taken off the queue.
+var enqueueScript = redis.NewScript(`
+local userID, key = ARGV[1], ARGV[2]
+if redis.call('HEXISTS', KEYS[4], userID) == 1 then
+	return {'matched'}
+end
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local entry = redis.call('HGET', KEYS[2], userID)
+if entry then
+	local deadline = tonumber(string.match(entry, '^(%d+)|'))
+	if deadline == 0 or deadline > now then
+		return {'queued'}
+	end
+end
+
+redis.call('HSET', KEYS[2], userID, '0|' .. key)
+redis.call('HSET', KEYS[3], userID, ARGV[3])
+local position = redis.call('RPUSH', KEYS[1], userID) - 1
+
+local result = {'ok', position}
+if redis.call('LLEN', KEYS[1]) < 2 then
+	return result
+end
+
+local lease = (now + tonumber(ARGV[4])) .. '|' .. key
+table.insert(result, lease)
+for _, id in ipairs(redis.call('LPOP', KEYS[1], 2)) do
+	redis.call('HSET', KEYS[2], id, lease)
+	table.insert(result, redis.call('HGET', KEYS[3], id))
+end
+return result
+`)
+
+// requeueScript puts players being paired back at the front of their queue.
+// Players no longer held by the lease they were paired under, because it
+// ran out and they queued again, are left alone.
+//
+// KEYS: queue list, queued hash, players hash
+// ARGV: queue key, then a user ID, player JSON and pairing lease for each
+// player in order
+var requeueScript = redis.NewScript(`
+local key = ARGV[1]
+for i = #ARGV - 2, 2, -3 do
+	local userID = ARGV[i]
+	if redis.call('HGET', KEYS[2], userID) == ARGV[i + 2] then
+		redis.call('HSET', KEYS[2], userID, '0|' .. key)
+		redis.call('HSET', KEYS[3], userID, ARGV[i + 1])
+		redis.call('LPUSH', KEYS[1], userID)
+	end
+end
+return 0
+`)
+
+// dequeueScript removes a waiting player from a queue if they are still in
+// it.
+//
+// KEYS: queue list, queued hash, players hash
+// ARGV: user ID, queue key
+// Returns the position the player held, or -1 if they weren't waiting in
+// the queue.
+var dequeueScript = redis.NewScript(`
+local userID = ARGV[1]
+if redis.call('HGET', KEYS[2], userID) ~= '0|' .. ARGV[2] then
+	return -1
+end
+
+local position = redis.call('LPOS', KEYS[1], userID)
+if not position then
+	return -1
+end
+
+redis.call('LREM', KEYS[1], 1, userID)
+redis.call('HDEL', KEYS[2], userID)
+redis.call('HDEL', KEYS[3], userID)
+return position
+`)
+
+// countQueuedScript releases players whose pairing lease has run out, so a
+// server that died while pairing doesn't leave them counted forever, and
+// returns the number of queued players.
+//
+// KEYS: queued hash, players hash
+var countQueuedScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local entries = redis.call('HGETALL', KEYS[1])
+for i = 1, #entries, 2 do
+	local deadline = tonumber(string.match(entries[i + 1], '^(%d+)|'))
+	if deadline ~= 0 and deadline <= now then
+		redis.call('HDEL', KEYS[1], entries[i])
+		redis.call('HDEL', KEYS[2], entries[i])
+	end
+end
+return redis.call('HLEN', KEYS[1])
+`)
+
+// createMatchScript stores a new match and takes its players out of the
+// queued and players hashes, but only while each player is still held by
+// the pairing lease the match was made under. A player whose lease ran out
+// may have queued again, and their new entry must be left alone.
+//
+// KEYS: match key, matches set, player match hash, game match hash, queued
+// hash, players hash
+// ARGV: match ID, match JSON, game ID, then a user ID and pairing lease for
+// each player, where an empty lease means the player was never queued
+// Returns 1 once the match is stored, or 0 if a lease was lost.
+var createMatchScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+for i = 4, #ARGV, 2 do
+	local entry = redis.call('HGET', KEYS[5], ARGV[i]) or ''
+	if entry ~= ARGV[i + 1] then
+		return 0
+	end
+	if entry ~= '' and tonumber(string.match(entry, '^(%d+)|')) <= now then
+		return 0
+	end
+end
+
+redis.call('SET', KEYS[1], ARGV[2])
+redis.call('SADD', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[4], ARGV[3], ARGV[1])
+for i = 4, #ARGV, 2 do
+	redis.call('HSET', KEYS[3], ARGV[i], ARGV[1])
+	redis.call('HDEL', KEYS[5], ARGV[i])
+	redis.call('HDEL', KEYS[6], ARGV[i])
+end
+return 1
+`)
+
+// RedisStore keeps matchmaking state in Redis so it survives restarts and
+// can be shared by several server instances. Every key starts with a
+// prefix, so one Redis database can hold more than one deployment.
+//
+// Keys, after the prefix:
+//
+//	queue:<queue key>  list of the user IDs waiting in a queue, in order
+//	queued             hash of user ID to lease deadline|queue key; see queuedSeparator
+//	players            hash of user ID to the JSON of a queued player
+//	match:<match ID>   JSON of an active match
+//	matches            set of active match IDs
+//	player_matches     hash of user ID to match ID
+//	game_matches       hash of engine game ID to match ID
+//	rotations          hash of opening rotation key to JSON progress
+//	events             pub/sub channel for events sent to players
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a matchmaking store keeping its keys in client
+// under prefix
+func NewRedisStore(client *redis.Client, prefix string) StoreInterface {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// publishedEvent is the message sent on the events channel
+type publishedEvent struct {
+	UserID int   `json:"userId"`
+	Event  Event `json:"event"`
+}
+
+// Restore reloads nothing: matches stay in Redis, where players connected
+// to other server instances are still playing them
+func (r *RedisStore) Restore(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (r *RedisStore) key(parts ...string) string {
+	return r.prefix + strings.Join(parts, ":")
+}
+
+func (r *RedisStore) queueKey(key string) string {
+	return r.key("queue", key)
+}
+
+func (r *RedisStore) matchKey(matchID string) string {
+	return r.key("match", matchID)
+}
+
+// Enqueue adds a player to a queue and takes the first two off it once it
+// holds a pair
+func (r *RedisStore) Enqueue(ctx context.Context, key string, player Player) (int, []Player, error) {
+	data, err := json.Marshal(player)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to encode player: %w", err)
+	}
+
+	result, err := enqueueScript.Run(ctx, r.client,
+		[]string{r.queueKey(key), r.key("queued"), r.key("players"), r.key("player_matches")},
+		player.UserID, key, data, pairingLease.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to join queue: %w", err)
+	}
+
+	switch result[0] {
+	case "matched":
+		return 0, nil, ErrAlreadyInMatch
+	case "queued":
+		return 0, nil, ErrAlreadyQueued
+	}
+
+	position := int(result[1].(int64))
+	if len(result) == 2 {
+		return position, nil, nil
+	}
+
+	lease := result[2].(string)
+	pair := make([]Player, 0, 2)
+	for _, item := range result[3:] {
+		var paired Player
+		if err := json.Unmarshal([]byte(item.(string)), &paired); err != nil {
+			return 0, nil, fmt.Errorf("failed to decode queued player: %w", err)
+		}
+		paired.lease = lease
+		pair = append(pair, paired)
+	}
+
+	return position, pair, nil
+}
+
+// Requeue puts players being paired back at the front of a queue
+func (r *RedisStore) Requeue(ctx context.Context, key string, players []Player) error {
+	args := []any{key}
+	for _, player := range players {
+		data, err := json.Marshal(player)
+		if err != nil {
+			return fmt.Errorf("failed to encode player: %w", err)
+		}
+		args = append(args, player.UserID, data, player.lease)
+	}
+
+	err := requeueScript.Run(ctx, r.client,
+		[]string{r.queueKey(key), r.key("queued"), r.key("players")},
+		args...,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to requeue players: %w", err)
+	}
+
+	return nil
+}
+
+// Dequeue removes a waiting player from their queue
+func (r *RedisStore) Dequeue(ctx context.Context, userID int) (string, int, error) {
+	entry, err := r.client.HGet(ctx, r.key("queued"), strconv.Itoa(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", -1, ErrNotQueued
+	}
+	if err != nil {
+		return "", -1, fmt.Errorf("failed to leave queue: %w", err)
+	}
+
+	// Players being paired can't leave
+	key, deadline := parseQueued(entry)
+	if deadline != 0 {
+		return "", -1, ErrNotQueued
+	}
+
+	position, err := dequeueScript.Run(ctx, r.client,
+		[]string{r.queueKey(key), r.key("queued"), r.key("players")},
+		userID, key,
+	).Int()
+	if err != nil {
+		return "", -1, fmt.Errorf("failed to leave queue: %w", err)
+	}
+	if position < 0 {
+		return "", -1, ErrNotQueued
+	}
+
+	return key, position, nil
+}
+
+// QueuePosition returns the key and position of a queued player
+func (r *RedisStore) QueuePosition(ctx context.Context, userID int) (string, int, error) {
+	entry, err := r.client.HGet(ctx, r.key("queued"), strconv.Itoa(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", -1, ErrNotQueued
+	}
+	if err != nil {
+		return "", -1, fmt.Errorf("failed to get queue position: %w", err)
+	}
+
+	// Taken off the queue to be paired
+	key, deadline := parseQueued(entry)
+	if deadline != 0 {
+		if time.Now().After(time.UnixMilli(deadline)) {
+			return "", -1, ErrNotQueued
+		}
+		return key, 0, nil
+	}
+
+	position, err := r.client.LPos(ctx, r.queueKey(key), strconv.Itoa(userID), redis.LPosArgs{}).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", -1, ErrNotQueued
+	}
+	if err != nil {
+		return "", -1, fmt.Errorf("failed to get queue position: %w", err)
+	}
+
+	return key, int(position), nil
+}
+
+// QueuedPlayers returns the user IDs waiting in a queue
+func (r *RedisStore) QueuedPlayers(ctx context.Context, key string) ([]int, error) {
+	members, err := r.client.LRange(ctx, r.queueKey(key), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+
+	userIDs := make([]int, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.Atoi(member)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q in queue %s", member, key)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, nil
+}
+
+// QueueKeys returns the keys of the queues holding waiting players
+func (r *RedisStore) QueueKeys(ctx context.Context) ([]string, error) {
+	prefix := r.queueKey("")
+
+	var keys []string
+	iter := r.client.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Shared reports true: every server instance pointed at the same keys
+// shares the queues
+func (r *RedisStore) Shared() bool {
+	return true
+}
+
+// CountQueued returns the number of queued players, releasing those whose
+// pairing lease has run out
+func (r *RedisStore) CountQueued(ctx context.Context) (int, error) {
+	count, err := countQueuedScript.Run(ctx, r.client,
+		[]string{r.key("queued"), r.key("players")},
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count queued players: %w", err)
+	}
+	return int(count), nil
+}
+
+// CreateMatch stores a new match and its player and game lookups in one
+// step, provided both players are still held by their pairing lease
+func (r *RedisStore) CreateMatch(ctx context.Context, match *Match) error {
+	data, err := encodeMatch(match)
+	if err != nil {
+		return err
+	}
+
+	stored, err := createMatchScript.Run(ctx, r.client,
+		[]string{
+			r.matchKey(match.ID), r.key("matches"), r.key("player_matches"),
+			r.key("game_matches"), r.key("queued"), r.key("players"),
+		},
+		match.ID, data, match.GameID,
+		match.Player1.UserID, match.Player1.lease,
+		match.Player2.UserID, match.Player2.lease,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to save match: %w", err)
+	}
+	if stored == 0 {
+		return ErrPairingExpired
+	}
+
+	return nil
+}
+
+// UpdateMatch applies update to a match under optimistic locking, retrying
+// if another server changes the match first
+func (r *RedisStore) UpdateMatch(ctx context.Context, matchID string, update func(*Match) error) (*Match, error) {
+	key := r.matchKey(matchID)
+
+	var updated *Match
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrMatchNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get match: %w", err)
+		}
+
+		match, err := decodeMatch(data)
+		if err != nil {
+			return err
+		}
+		if err := update(match); err != nil {
+			return err
+		}
+
+		encoded, err := encodeMatch(match)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if !isTerminal(match.Status) {
+				pipe.Set(ctx, key, encoded, 0)
+				return nil
+			}
+
+			pipe.Del(ctx, key)
+			pipe.SRem(ctx, r.key("matches"), matchID)
+			pipe.HDel(ctx, r.key("player_matches"), strconv.Itoa(match.Player1.UserID), strconv.Itoa(match.Player2.UserID))
+			pipe.HDel(ctx, r.key("game_matches"), match.GameID)
+			return nil
+		})
+		updated = match
+		return err
+	}
+
+	for range maxUpdateAttempts {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return updated, nil
+	}
+
+	return nil, fmt.Errorf("failed to update match %s: too many concurrent updates", matchID)
+}
+
+// GetMatch returns an active match
+func (r *RedisStore) GetMatch(ctx context.Context, matchID string) (*Match, error) {
+	data, err := r.client.Get(ctx, r.matchKey(matchID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+
+	return decodeMatch(data)
+}
+
+// MatchIDForPlayer returns the ID of a player's active match
+func (r *RedisStore) MatchIDForPlayer(ctx context.Context, userID int) (string, error) {
+	return r.lookupMatchID(ctx, "player_matches", strconv.Itoa(userID))
+}
+
+// MatchIDForGame returns the ID of the match hosting an engine game
+func (r *RedisStore) MatchIDForGame(ctx context.Context, gameID string) (string, error) {
+	return r.lookupMatchID(ctx, "game_matches", gameID)
+}
+
+func (r *RedisStore) lookupMatchID(ctx context.Context, hash, field string) (string, error) {
+	matchID, err := r.client.HGet(ctx, r.key(hash), field).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMatchNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up match: %w", err)
+	}
+	return matchID, nil
+}
+
+// ListMatches returns every active match
+func (r *RedisStore) ListMatches(ctx context.Context) ([]*Match, error) {
+	matchIDs, err := r.client.SMembers(ctx, r.key("matches")).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list matches: %w", err)
+	}
+	if len(matchIDs) == 0 {
+		return []*Match{}, nil
+	}
+
+	keys := make([]string, 0, len(matchIDs))
+	for _, matchID := range matchIDs {
+		keys = append(keys, r.matchKey(matchID))
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list matches: %w", err)
+	}
+
+	matches := make([]*Match, 0, len(values))
+	for _, value := range values {
+		// Ended between the two reads
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		match, err := decodeMatch([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// CountMatches returns the number of active matches
+func (r *RedisStore) CountMatches(ctx context.Context) (int, error) {
+	count, err := r.client.SCard(ctx, r.key("matches")).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count matches: %w", err)
+	}
+	return int(count), nil
+}
+
+// OpeningRotation returns a pair's progress through an opening suite
+func (r *RedisStore) OpeningRotation(ctx context.Context, key string) (OpeningRotation, error) {
+	var rotation OpeningRotation
+
+	data, err := r.client.HGet(ctx, r.key("rotations"), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return rotation, nil
+	}
+	if err != nil {
+		return rotation, fmt.Errorf("failed to get opening rotation: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &rotation); err != nil {
+		return rotation, fmt.Errorf("failed to decode opening rotation: %w", err)
+	}
+	return rotation, nil
+}
+
+// SaveOpeningRotation records a pair's progress through an opening suite
+func (r *RedisStore) SaveOpeningRotation(ctx context.Context, key string, rotation OpeningRotation) error {
+	data, err := json.Marshal(rotation)
+	if err != nil {
+		return fmt.Errorf("failed to encode opening rotation: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, r.key("rotations"), key, data).Err(); err != nil {
+		return fmt.Errorf("failed to save opening rotation: %w", err)
+	}
+	return nil
+}
+
+// PublishEvent sends an event to every server listening on the events
+// channel
+func (r *RedisStore) PublishEvent(ctx context.Context, userID int, event Event) error {
+	data, err := json.Marshal(publishedEvent{UserID: userID, Event: event})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	if err := r.client.Publish(ctx, r.key("events"), data).Err(); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// ListenEvents subscribes to the events channel and passes every event to
+// deliver until ctx is cancelled or the subscription breaks
+func (r *RedisStore) ListenEvents(ctx context.Context, deliver func(userID int, event Event)) error {
+	sub := r.client.Subscribe(ctx, r.key("events"))
+	defer sub.Close()
+
+	// Wait for the subscription so events published afterwards aren't missed
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, open := <-messages:
+			if !open {
+				return errors.New("event subscription closed")
+			}
+
+			var published publishedEvent
+			if err := json.Unmarshal([]byte(message.Payload), &published); err != nil {
+				continue
+			}
+			deliver(published.UserID, published.Event)
+		}
+	}
+}
+
+// parseQueued splits an entry of the queued hash into its queue key and
+// lease deadline
+func parseQueued(entry string) (string, int64) {
+	deadline, key, _ := strings.Cut(entry, queuedSeparator)
+	millis, _ := strconv.ParseInt(deadline, 10, 64)
+	return key, millis
+}
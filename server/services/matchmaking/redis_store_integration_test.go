@@ -0,0 +1,461 @@
+//go:build integration
+
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// These tests need a Redis server. Run them with
+//
+//	go test -tags integration ./services/matchmaking/
+//
+// against REDIS_URL, by default a local Redis. Each test works under its own
+// key prefix and deletes its keys afterwards.
+
+// testQueueKey is the queue a 5 minute standard game is played from
+var testQueueKey = GameSettings{TimeControl: TimeControl{BaseSeconds: 300}}.normalize().Key()
+
+// newTestClient connects to the test Redis server, skipping the test if it
+// isn't reachable
+func newTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		url = "redis://localhost:6379/0"
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("invalid REDIS_URL: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("Redis is not available at %s: %v", url, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// newTestPrefix returns a key prefix unique to the test and deletes every key
+// under it once the test ends
+func newTestPrefix(t *testing.T, client *redis.Client) string {
+	t.Helper()
+
+	prefix := fmt.Sprintf("checkmait-test:%s:%d:", t.Name(), time.Now().UnixNano())
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, err := client.Keys(ctx, prefix+"*").Result()
+		if err == nil && len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+	})
+	return prefix
+}
+
+func newTestStore(t *testing.T) StoreInterface {
+	t.Helper()
+
+	client := newTestClient(t)
+	return NewRedisStore(client, newTestPrefix(t, client))
+}
+
+func testPlayer(userID int) Player {
+	return Player{
+		UserID:   userID,
+		ModelID:  userID * 10,
+		JoinedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func testMatch(id string, player1, player2 int) *Match {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &Match{
+		ID:          id,
+		Player1:     testPlayer(player1),
+		Player2:     testPlayer(player2),
+		GameID:      "game-" + id,
+		WhitePlayer: player1,
+		BlackPlayer: player2,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Status:      StatusMatched,
+		TimeControl: TimeControl{BaseSeconds: 300},
+		Clock:       &Clock{WhiteMillis: 300000, BlackMillis: 300000, Turn: "white"},
+	}
+}
+
+func TestRedisStoreEnqueuePairsFirstTwoPlayers(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	position, pair, err := store.Enqueue(ctx, testQueueKey, testPlayer(1))
+	if err != nil {
+		t.Fatalf("Enqueue(1): %v", err)
+	}
+	if position != 0 || pair != nil {
+		t.Fatalf("Enqueue(1) = %d, %v; want 0, no pair", position, pair)
+	}
+
+	if _, _, err := store.Enqueue(ctx, testQueueKey, testPlayer(1)); !errors.Is(err, ErrAlreadyQueued) {
+		t.Fatalf("Enqueue(1) again: got %v, want ErrAlreadyQueued", err)
+	}
+
+	position, pair, err = store.Enqueue(ctx, testQueueKey, testPlayer(2))
+	if err != nil {
+		t.Fatalf("Enqueue(2): %v", err)
+	}
+	if position != 1 || len(pair) != 2 || pair[0].UserID != 1 || pair[1].UserID != 2 {
+		t.Fatalf("Enqueue(2) = %d, %+v; want 1 and players 1 and 2", position, pair)
+	}
+	if pair[0].ModelID != 10 {
+		t.Errorf("paired player lost its model: %+v", pair[0])
+	}
+
+	// Players being paired are still queued, at the front
+	key, position, err := store.QueuePosition(ctx, 2)
+	if err != nil || key != testQueueKey || position != 0 {
+		t.Errorf("QueuePosition(2) = %q, %d, %v; want %q, 0", key, position, err, testQueueKey)
+	}
+	if _, _, err := store.Dequeue(ctx, 2); !errors.Is(err, ErrNotQueued) {
+		t.Errorf("Dequeue(2) while pairing: got %v, want ErrNotQueued", err)
+	}
+
+	queued, err := store.QueuedPlayers(ctx, testQueueKey)
+	if err != nil || len(queued) != 0 {
+		t.Errorf("QueuedPlayers = %v, %v; want an empty queue", queued, err)
+	}
+	if count, err := store.CountQueued(ctx); err != nil || count != 2 {
+		t.Errorf("CountQueued = %d, %v; want 2", count, err)
+	}
+}
+
+func TestRedisStoreConcurrentJoinsPairEachPlayerOnce(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	prefix := newTestPrefix(t, client)
+
+	// Two stores over separate connections stand in for two servers
+	other := redis.NewClient(client.Options())
+	t.Cleanup(func() { other.Close() })
+	stores := []StoreInterface{NewRedisStore(client, prefix), NewRedisStore(other, prefix)}
+
+	const players = 40
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		paired = make(map[int]int)
+	)
+	for userID := 1; userID <= players; userID++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, pair, err := stores[userID%2].Enqueue(ctx, testQueueKey, testPlayer(userID))
+			if err != nil {
+				t.Errorf("Enqueue(%d): %v", userID, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, player := range pair {
+				paired[player.UserID]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(paired) != players {
+		t.Errorf("%d players paired, want %d", len(paired), players)
+	}
+	for userID, times := range paired {
+		if times != 1 {
+			t.Errorf("player %d paired %d times", userID, times)
+		}
+	}
+}
+
+func TestRedisStoreRequeueAndDequeue(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	var pair []Player
+	for _, userID := range []int{1, 2, 3} {
+		_, paired, err := store.Enqueue(ctx, testQueueKey, testPlayer(userID))
+		if err != nil {
+			t.Fatalf("Enqueue(%d): %v", userID, err)
+		}
+		if paired != nil {
+			pair = paired
+		}
+	}
+
+	// 1 and 2 were paired; putting them back keeps them ahead of 3
+	if err := store.Requeue(ctx, testQueueKey, pair); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	queued, err := store.QueuedPlayers(ctx, testQueueKey)
+	if err != nil || fmt.Sprint(queued) != "[1 2 3]" {
+		t.Fatalf("QueuedPlayers = %v, %v; want [1 2 3]", queued, err)
+	}
+
+	key, position, err := store.Dequeue(ctx, 2)
+	if err != nil || key != testQueueKey || position != 1 {
+		t.Fatalf("Dequeue(2) = %q, %d, %v; want %q, 1", key, position, err, testQueueKey)
+	}
+	if _, _, err := store.QueuePosition(ctx, 2); !errors.Is(err, ErrNotQueued) {
+		t.Errorf("QueuePosition(2) after leaving: got %v, want ErrNotQueued", err)
+	}
+	if _, position, err := store.QueuePosition(ctx, 3); err != nil || position != 1 {
+		t.Errorf("QueuePosition(3) = %d, %v; want 1", position, err)
+	}
+	if _, _, err := store.Dequeue(ctx, 2); !errors.Is(err, ErrNotQueued) {
+		t.Errorf("Dequeue(2) twice: got %v, want ErrNotQueued", err)
+	}
+}
+
+func TestRedisStoreMatchesSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	prefix := newTestPrefix(t, client)
+	store := NewRedisStore(client, prefix)
+
+	if _, _, err := store.Enqueue(ctx, testQueueKey, testPlayer(1)); err != nil {
+		t.Fatalf("Enqueue(1): %v", err)
+	}
+	_, pair, err := store.Enqueue(ctx, testQueueKey, testPlayer(2))
+	if err != nil {
+		t.Fatalf("Enqueue(2): %v", err)
+	}
+
+	match := testMatch("m1", 1, 2)
+	match.Player1, match.Player2 = pair[0], pair[1]
+	match.imbalance = imbalanceStreak{leader: "white", plies: 4}
+	if err := store.CreateMatch(ctx, match); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	if count, err := store.CountQueued(ctx); err != nil || count != 0 {
+		t.Errorf("CountQueued after match = %d, %v; want 0", count, err)
+	}
+	if _, _, err := store.Enqueue(ctx, testQueueKey, testPlayer(1)); !errors.Is(err, ErrAlreadyInMatch) {
+		t.Errorf("Enqueue(1) in a match: got %v, want ErrAlreadyInMatch", err)
+	}
+
+	// A new client stands in for a restarted server
+	restartedClient := redis.NewClient(client.Options())
+	t.Cleanup(func() { restartedClient.Close() })
+	restarted := NewRedisStore(restartedClient, prefix)
+
+	loaded, err := restarted.GetMatch(ctx, "m1")
+	if err != nil {
+		t.Fatalf("GetMatch after restart: %v", err)
+	}
+	if loaded.GameID != match.GameID || loaded.Clock == nil || loaded.Clock.WhiteMillis != 300000 || !loaded.CreatedAt.Equal(match.CreatedAt) {
+		t.Errorf("GetMatch = %+v; want %+v", loaded, match)
+	}
+	if loaded.imbalance != match.imbalance {
+		t.Errorf("adjudication state = %+v; want %+v", loaded.imbalance, match.imbalance)
+	}
+
+	for _, userID := range []int{1, 2} {
+		if matchID, err := restarted.MatchIDForPlayer(ctx, userID); err != nil || matchID != "m1" {
+			t.Errorf("MatchIDForPlayer(%d) = %q, %v; want m1", userID, matchID, err)
+		}
+	}
+	if matchID, err := restarted.MatchIDForGame(ctx, "game-m1"); err != nil || matchID != "m1" {
+		t.Errorf("MatchIDForGame = %q, %v; want m1", matchID, err)
+	}
+	if matches, err := restarted.ListMatches(ctx); err != nil || len(matches) != 1 {
+		t.Errorf("ListMatches = %d matches, %v; want 1", len(matches), err)
+	}
+}
+
+func TestRedisStoreExpiredPairingLease(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	prefix := newTestPrefix(t, client)
+	store := NewRedisStore(client, prefix)
+
+	if _, _, err := store.Enqueue(ctx, testQueueKey, testPlayer(1)); err != nil {
+		t.Fatalf("Enqueue(1): %v", err)
+	}
+	_, pair, err := store.Enqueue(ctx, testQueueKey, testPlayer(2))
+	if err != nil {
+		t.Fatalf("Enqueue(2): %v", err)
+	}
+
+	// Player 1's lease runs out, as if the pairing server had died
+	if err := client.HSet(ctx, prefix+"queued", "1", "1"+queuedSeparator+testQueueKey).Err(); err != nil {
+		t.Fatalf("expiring lease: %v", err)
+	}
+	if count, err := store.CountQueued(ctx); err != nil || count != 1 {
+		t.Fatalf("CountQueued with an expired lease = %d, %v; want 1", count, err)
+	}
+
+	// Released, player 1 queues again and the late match must not take them
+	if _, _, err := store.Enqueue(ctx, testQueueKey, testPlayer(1)); err != nil {
+		t.Fatalf("Enqueue(1) after expiry: %v", err)
+	}
+	match := testMatch("m1", 1, 2)
+	match.Player1, match.Player2 = pair[0], pair[1]
+	if err := store.CreateMatch(ctx, match); !errors.Is(err, ErrPairingExpired) {
+		t.Fatalf("CreateMatch with a lost lease: got %v, want ErrPairingExpired", err)
+	}
+	if _, err := store.GetMatch(ctx, "m1"); !errors.Is(err, ErrMatchNotFound) {
+		t.Errorf("GetMatch after refused match: got %v, want ErrMatchNotFound", err)
+	}
+
+	// Requeueing the failed pair leaves player 1's new place alone
+	if err := store.Requeue(ctx, testQueueKey, pair); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	queued, err := store.QueuedPlayers(ctx, testQueueKey)
+	if err != nil || fmt.Sprint(queued) != "[2 1]" {
+		t.Errorf("QueuedPlayers = %v, %v; want [2 1]", queued, err)
+	}
+}
+
+func TestRedisStoreUpdateMatch(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	if err := store.CreateMatch(ctx, testMatch("m1", 1, 2)); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	updated, err := store.UpdateMatch(ctx, "m1", func(match *Match) error {
+		match.Status = StatusInProgress
+		match.Plies = 3
+		return nil
+	})
+	if err != nil || updated.Status != StatusInProgress {
+		t.Fatalf("UpdateMatch = %+v, %v; want in progress", updated, err)
+	}
+
+	errRejected := errors.New("rejected")
+	if _, err := store.UpdateMatch(ctx, "m1", func(match *Match) error {
+		match.Plies = 99
+		return errRejected
+	}); !errors.Is(err, errRejected) {
+		t.Fatalf("failing UpdateMatch: got %v, want errRejected", err)
+	}
+	if match, err := store.GetMatch(ctx, "m1"); err != nil || match.Plies != 3 {
+		t.Fatalf("GetMatch after failed update = %+v, %v; want 3 plies", match, err)
+	}
+
+	// Concurrent updates are applied one after the other
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.UpdateMatch(ctx, "m1", func(match *Match) error {
+				match.Plies++
+				return nil
+			}); err != nil {
+				t.Errorf("concurrent UpdateMatch: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if match, err := store.GetMatch(ctx, "m1"); err != nil || match.Plies != 13 {
+		t.Errorf("GetMatch after concurrent updates = %d plies, %v; want 13", match.Plies, err)
+	}
+
+	// Ending the match releases it and its players
+	if _, err := store.UpdateMatch(ctx, "m1", func(match *Match) error {
+		match.Status = StatusCompleted
+		return nil
+	}); err != nil {
+		t.Fatalf("completing UpdateMatch: %v", err)
+	}
+	if _, err := store.GetMatch(ctx, "m1"); !errors.Is(err, ErrMatchNotFound) {
+		t.Errorf("GetMatch after completion: got %v, want ErrMatchNotFound", err)
+	}
+	if _, err := store.MatchIDForPlayer(ctx, 1); !errors.Is(err, ErrMatchNotFound) {
+		t.Errorf("MatchIDForPlayer after completion: got %v, want ErrMatchNotFound", err)
+	}
+	if count, err := store.CountMatches(ctx); err != nil || count != 0 {
+		t.Errorf("CountMatches after completion = %d, %v; want 0", count, err)
+	}
+	if _, err := store.UpdateMatch(ctx, "m1", func(*Match) error { return nil }); !errors.Is(err, ErrMatchNotFound) {
+		t.Errorf("UpdateMatch after completion: got %v, want ErrMatchNotFound", err)
+	}
+}
+
+func TestRedisStoreOpeningRotation(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	key := rotationKey("suite", "", testPlayer(1), testPlayer(2))
+
+	rotation, err := store.OpeningRotation(ctx, key)
+	if err != nil || rotation != (OpeningRotation{}) {
+		t.Fatalf("OpeningRotation before saving = %+v, %v; want the zero value", rotation, err)
+	}
+
+	saved := OpeningRotation{Played: 3, LastWhite: 2}
+	if err := store.SaveOpeningRotation(ctx, key, saved); err != nil {
+		t.Fatalf("SaveOpeningRotation: %v", err)
+	}
+	if rotation, err := store.OpeningRotation(ctx, key); err != nil || rotation != saved {
+		t.Errorf("OpeningRotation = %+v, %v; want %+v", rotation, err, saved)
+	}
+}
+
+func TestRedisStoreEventsReachOtherServers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newTestClient(t)
+	prefix := newTestPrefix(t, client)
+	listener := redis.NewClient(client.Options())
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan publishedEvent, 1)
+	listening := make(chan error, 1)
+	go func() {
+		listening <- NewRedisStore(listener, prefix).ListenEvents(ctx, func(userID int, event Event) {
+			select {
+			case received <- publishedEvent{UserID: userID, Event: event}:
+			default:
+			}
+		})
+	}()
+
+	// The subscription starts asynchronously, so publish until it arrives
+	publisher := NewRedisStore(client, prefix)
+	event := Event{Type: EventQueuePosition, QueuePosition: 4}
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for {
+		if err := publisher.PublishEvent(ctx, 7, event); err != nil {
+			t.Fatalf("PublishEvent: %v", err)
+		}
+
+		select {
+		case got := <-received:
+			if got.UserID != 7 || got.Event.Type != event.Type || got.Event.QueuePosition != 4 {
+				t.Fatalf("received %+v; want user 7 and %+v", got, event)
+			}
+			cancel()
+			if err := <-listening; err != nil {
+				t.Errorf("ListenEvents returned %v after cancellation", err)
+			}
+			return
+		case <-ticker.C:
+		case <-timeout:
+			t.Fatal("event was not delivered")
+		}
+	}
+}
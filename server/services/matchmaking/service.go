@@ -1,16 +1,44 @@
 package matchmaking
 
 import (
+	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/engine"
+	"github.com/ajlaz/checkmAIt/server/services/opening"
+)
+
+// Player colors
+const (
+	ColorWhite = "white"
+	ColorBlack = "black"
 )
 
+// ErrMatchNotFound is returned when no active match matches a lookup
+var ErrMatchNotFound = errors.New("match not found")
+
+// ErrShuttingDown is returned when a player joins a queue after the server
+// has started shutting down
+var ErrShuttingDown = errors.New("matchmaking is shutting down")
+
 // Player represents a user in the matchmaking queue
 type Player struct {
 	UserID    int        `json:"userId"`
 	ModelID   int        `json:"modelId"`
 	JoinedAt  time.Time  `json:"joinedAt"`
 	MatchedAt *time.Time `json:"matchedAt,omitempty"`
+	Connected bool       `json:"connected"` // Whether the player has joined the engine game
+
+	Settings GameSettings `json:"settings"` // Settings the player queued with
+
+	// Set while a disconnected player may still reconnect to their match
+	ReconnectDeadline *time.Time `json:"reconnectDeadline,omitempty"`
+
+	lease string // Pairing lease of the Redis store, checked when the match is created
 }
 
 // Match represents a pairing between two players
@@ -20,53 +48,135 @@ type Match struct {
 	Player2     Player    `json:"player2"`
 	GameID      string    `json:"gameId"`
 	WSPort      int       `json:"wsPort"`
+	Engine      string    `json:"engine"`      // Base URL of the engine hosting the game
 	WhitePlayer int       `json:"whitePlayer"` // UserID of white player
 	BlackPlayer int       `json:"blackPlayer"` // UserID of black player
 	CreatedAt   time.Time `json:"createdAt"`
-	Status      string    `json:"status"` // "waiting", "matched", "in_progress", "completed", "error"
+	UpdatedAt   time.Time `json:"updatedAt"` // When the match entered its current status
+	Status      string    `json:"status"`    // One of the Status* constants
+
+	Variant     string      `json:"variant"`
+	TimeControl TimeControl `json:"timeControl"`
+	Clock       *Clock      `json:"clock,omitempty"` // Nil for untimed games
+	Plies       int         `json:"plies"`           // Moves played by both sides
+
+	// Starting position from the players' opening suite; nil for the
+	// standard initial position
+	Opening *opening.Opening `json:"opening,omitempty"`
+
+	imbalance imbalanceStreak // Decisive material lead tracked for adjudication
+
+	Result    *GameResult `json:"result,omitempty"`
+	EndReason string      `json:"endReason,omitempty"`
+	EndedAt   *time.Time  `json:"endedAt,omitempty"`
 }
 
 // ServiceInterface defines the contract for the matchmaking service
 type ServiceInterface interface {
-	// AddToQueue adds a player to the matchmaking queue and attempts to find a match
-	AddToQueue(userID int, modelID int) (*Match, error)
+	// AddToQueue adds a player to the queue for their game settings and attempts to find a match
+	AddToQueue(ctx context.Context, userID int, modelID int, settings GameSettings) (*Match, error)
 
 	// GetPlayerStatus gets the match status for a player or their position in queue
-	GetPlayerStatus(userID int) (*Match, int, error)
+	GetPlayerStatus(ctx context.Context, userID int) (*Match, int, error)
+
+	// GetMatchByGameID looks up the active match hosting an engine game
+	GetMatchByGameID(ctx context.Context, gameID string) (*Match, error)
 
 	// RemoveFromQueue removes a player from the queue
-	RemoveFromQueue(userID int) error
+	RemoveFromQueue(ctx context.Context, userID int) error
 
 	// RemoveMatch removes a match and cleans up player mappings
-	RemoveMatch(matchID string) error
+	RemoveMatch(ctx context.Context, matchID string) error
+
+	// ListMatches returns a snapshot of every active match, oldest first
+	ListMatches(ctx context.Context) ([]*Match, error)
+
+	// CancelMatch ends an active match without a result and releases its
+	// engine game
+	CancelMatch(ctx context.Context, matchID, reason string) error
 
 	// RemovePlayerFromMatch removes a player from their current match
-	RemovePlayerFromMatch(userID int) error
+	RemovePlayerFromMatch(ctx context.Context, userID int) error
 
-	// GetQueueStats returns statistics about the current queue
-	GetQueueStats() (int, int)
+	// MarkPlayerDisconnected starts the reconnect grace period for a player
+	MarkPlayerDisconnected(ctx context.Context, userID int) error
+
+	// GetQueueStats returns the number of queued players across all queues
+	// and the number of active matches
+	GetQueueStats(ctx context.Context) (int, int)
+
+	// HandleGameEvent applies an engine event to the match hosting gameID
+	HandleGameEvent(ctx context.Context, gameID string, event GameEvent) error
+
+	// StartReaper expires matches stuck in a status past its deadline until
+	// ctx is cancelled
+	StartReaper(ctx context.Context)
+
+	// Restore reloads the matches kept from before a restart and reconciles
+	// them with the games live on the engines
+	Restore(ctx context.Context) error
+
+	// DeleteOrphanGames deletes the engine games no active match refers to.
+	// It waits out a grace period first, so it is run in the background.
+	DeleteOrphanGames(ctx context.Context)
+
+	// Drain stops taking queue joins, takes players off queues that won't
+	// outlive this server, notifying them, and ends every event stream
+	Drain(ctx context.Context) error
+
+	// StartEventRelay delivers events published by every server instance
+	// to this instance's subscribers until ctx is cancelled
+	StartEventRelay(ctx context.Context)
+
+	// Subscribe streams queue and match events for a player until the
+	// returned cancel function is called
+	Subscribe(userID int) (<-chan Event, func())
 }
 
-// Service implements the matchmaking service with thread-safe operations
+// Service implements the matchmaking service over a store holding the queues
+// and matches, so several server instances can share them
 type Service struct {
-	queue         []Player          // Queue of players waiting to be matched
-	matches       map[string]*Match // Map of active matches by match ID
-	playerMatches map[int]string    // Maps player IDs to match IDs for quick lookup
-	mu            sync.RWMutex      // RWMutex for thread-safe operations
+	store         StoreInterface
 	engineService EngineServiceInterface
+	recorder      GameRecorderInterface
+	openings      OpeningBookInterface
+	lifecycle     LifecycleConfig
+	adjudication  AdjudicationConfig
+
+	subscribers map[int]map[chan Event]struct{} // Event listeners on this instance by player ID
+	subMu       sync.Mutex
+
+	draining atomic.Bool // Set once the server starts shutting down
 }
 
 // EngineServiceInterface defines the contract for interaction with the chess engine
 type EngineServiceInterface interface {
-	CreateGame(gameID, player1ID, player1ModelID, player2ID, player2ModelID string) (string, int, error)
+	CreateGame(ctx context.Context, gameID, player1ID, player1ModelID, player2ID, player2ModelID string, options engine.GameOptions) (*engine.Game, error)
+	DeleteGame(ctx context.Context, engineURL, gameID string) error
+	ListGames(ctx context.Context, engineURL string) ([]string, error)
+	Instances() []engine.InstanceStatus
+}
+
+// GameRecorderInterface defines the contract for recording finished games
+type GameRecorderInterface interface {
+	RecordGame(ctx context.Context, game *model.Game) (*model.Game, error)
+}
+
+// OpeningBookInterface defines the contract for looking up opening suites
+type OpeningBookInterface interface {
+	Suite(name string) (*opening.Suite, error)
 }
 
-// NewService creates a new matchmaking service instance
-func NewService(engineService EngineServiceInterface) ServiceInterface {
+// NewService creates a new matchmaking service instance keeping its state
+// in store
+func NewService(store StoreInterface, engineService EngineServiceInterface, recorder GameRecorderInterface, openings OpeningBookInterface, lifecycle LifecycleConfig, adjudication AdjudicationConfig) ServiceInterface {
 	return &Service{
-		queue:         make([]Player, 0),
-		matches:       make(map[string]*Match),
-		playerMatches: make(map[int]string),
+		store:         store,
 		engineService: engineService,
+		recorder:      recorder,
+		openings:      openings,
+		lifecycle:     lifecycle,
+		adjudication:  adjudication,
+		subscribers:   make(map[int]map[chan Event]struct{}),
 	}
 }
@@ -1,10 +1,15 @@
 package matchmaking
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"sort"
 	"time"
+
+	"github.com/ajlaz/checkmAIt/server/logging"
+	"github.com/ajlaz/checkmAIt/server/metrics"
+	"github.com/ajlaz/checkmAIt/server/services/engine"
 )
 
 // Helper function to generate a unique ID
@@ -17,201 +22,268 @@ func generateGameID() string {
 	return fmt.Sprintf("game-%d", time.Now().UnixNano())
 }
 
-// AddToQueue adds a player to the matchmaking queue and attempts to find a match
-func (s *Service) AddToQueue(userID, modelID int) (*Match, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// AddToQueue adds a player to the queue for their game settings and attempts
+// to find a match
+func (s *Service) AddToQueue(ctx context.Context, userID, modelID int, settings GameSettings) (*Match, error) {
+	if s.draining.Load() {
+		return nil, ErrShuttingDown
+	}
 
-	// Check if user is already in a match
-	if matchID, exists := s.playerMatches[userID]; exists {
-		if match, found := s.matches[matchID]; found {
-			return match, nil
-		}
+	settings = settings.normalize()
+	if err := s.validateSettings(settings); err != nil {
+		return nil, err
 	}
 
-	// Check if user is already in the queue
-	for _, player := range s.queue {
-		if player.UserID == userID {
-			return nil, errors.New("user is already in the matchmaking queue")
-		}
+	// Check if user is already in a match
+	match, err := s.playerMatch(ctx, userID)
+	if err == nil {
+		return match, nil
+	}
+	if !errors.Is(err, ErrMatchNotFound) {
+		return nil, err
 	}
 
-	// Add the player to the queue
-	player := Player{
+	// Add the player to the queue for their settings; the store takes the
+	// first two players off the queue once it holds a pair (FIFO)
+	key := settings.Key()
+	position, pair, err := s.store.Enqueue(ctx, key, Player{
 		UserID:   userID,
 		ModelID:  modelID,
 		JoinedAt: time.Now(),
+		Settings: settings,
+	})
+	if errors.Is(err, ErrAlreadyInMatch) {
+		// Paired by another request since the check above
+		return s.playerMatch(ctx, userID)
 	}
-	s.queue = append(s.queue, player)
-
-	// Try to find a match (FIFO)
-	if len(s.queue) > 1 {
-		// Match the first two players in the queue
-		player1 := s.queue[0]
-		player2 := s.queue[1]
-
-		// Remove matched players from queue
-		s.queue = s.queue[2:]
-
-		// Create the match
-		matchID := generateMatchID()
-		gameID := generateGameID()
-		now := time.Now()
-
-		// Update matched time for players
-		player1.MatchedAt = &now
-		player2.MatchedAt = &now
-
-		// Randomly assign colors
-		var whitePlayerID, blackPlayerID int
-		var whiteModelID, blackModelID int
-		if rand.Intn(2) == 0 {
-			whitePlayerID = player1.UserID
-			whiteModelID = player1.ModelID
-			blackPlayerID = player2.UserID
-			blackModelID = player2.ModelID
-		} else {
-			whitePlayerID = player2.UserID
-			whiteModelID = player2.ModelID
-			blackPlayerID = player1.UserID
-			blackModelID = player1.ModelID
-		}
-		// Create a game via engine service
-		returnedGameID, wsPort, err := s.engineService.CreateGame(
-			gameID,
-			fmt.Sprintf("%d", whitePlayerID), fmt.Sprintf("%d", whiteModelID),
-			fmt.Sprintf("%d", blackPlayerID), fmt.Sprintf("%d", blackModelID),
-		)
-
-		if err != nil {
-			// If creating the game fails, put the players back in the queue
-			s.queue = append([]Player{player1, player2}, s.queue...)
-			return nil, fmt.Errorf("failed to create game: %w", err)
-		}
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, userID, Event{Type: EventQueuePosition, QueuePosition: position})
 
-		// Create and store the match
-		match := &Match{
-			ID:          matchID,
-			Player1:     player1,
-			Player2:     player2,
-			GameID:      returnedGameID,
-			WSPort:      wsPort,
-			WhitePlayer: whitePlayerID,
-			BlackPlayer: blackPlayerID,
-			CreatedAt:   now,
-			Status:      "matched",
-		}
-		s.matches[matchID] = match
+	// Return nil if no match was made (player is still in queue)
+	if pair == nil {
+		return nil, nil
+	}
+
+	// Both players are now out of the queue, so the match is created even if
+	// this request goes away
+	ctx = context.WithoutCancel(ctx)
+	match, err = s.createMatch(ctx, key, settings, pair[0], pair[1])
+	s.publishQueuePositions(ctx, key, 0)
+	return match, err
+}
 
-		// Add player-to-match mapping for quick lookup
-		s.playerMatches[player1.UserID] = matchID
-		s.playerMatches[player2.UserID] = matchID
+// createMatch creates the engine game and match for two players taken off
+// the queue for key. If that fails, the players are put back at the front
+// of the queue.
+func (s *Service) createMatch(ctx context.Context, key string, settings GameSettings, player1, player2 Player) (*Match, error) {
+	start := time.Now()
+
+	// Assign colors and the starting position
+	pairing, err := s.pair(ctx, settings, player1, player2)
+	if err != nil {
+		s.requeue(ctx, key, player1, player2)
+		return nil, err
+	}
 
-		return match, nil
+	options := engine.GameOptions{Variant: settings.Variant}
+	if pairing.opening != nil {
+		options.StartFEN = pairing.opening.FEN
 	}
 
-	// Return nil if no match was made (player is still in queue)
-	return nil, nil
+	// Create a game via engine service
+	game, err := s.engineService.CreateGame(
+		ctx,
+		generateGameID(),
+		fmt.Sprintf("%d", pairing.white.UserID), fmt.Sprintf("%d", pairing.white.ModelID),
+		fmt.Sprintf("%d", pairing.black.UserID), fmt.Sprintf("%d", pairing.black.ModelID),
+		options,
+	)
+	if err != nil {
+		s.requeue(ctx, key, player1, player2)
+		return nil, fmt.Errorf("failed to create game: %w", err)
+	}
+
+	logger := logging.FromContext(ctx)
+	if err := pairing.commit(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Failed to save opening suite progress")
+	}
+
+	// Update matched time for players
+	now := time.Now()
+	player1.MatchedAt = &now
+	player2.MatchedAt = &now
+
+	match := &Match{
+		ID:          generateMatchID(),
+		Player1:     player1,
+		Player2:     player2,
+		GameID:      game.ID,
+		WSPort:      game.WSPort,
+		Engine:      game.Engine,
+		WhitePlayer: pairing.white.UserID,
+		BlackPlayer: pairing.black.UserID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Status:      StatusMatched,
+		Variant:     settings.Variant,
+		TimeControl: settings.TimeControl,
+		Clock:       newClock(settings.TimeControl),
+		Opening:     pairing.opening,
+	}
+	if err := s.store.CreateMatch(ctx, match); err != nil {
+		if deleteErr := s.engineService.DeleteGame(ctx, game.Engine, game.ID); deleteErr != nil {
+			logger.Warn().Err(deleteErr).Str("game_id", game.ID).Msg("Failed to delete game of unsaved match from engine")
+		}
+		s.requeue(ctx, key, player1, player2)
+		return nil, fmt.Errorf("failed to save match: %w", err)
+	}
+
+	s.publishMatch(ctx, EventMatchFound, match, "")
+	metrics.MatchCreationDuration.Observe(time.Since(start).Seconds())
+
+	return match, nil
 }
 
-// GetPlayerStatus returns the match for a player if matched, or their position in queue
-func (s *Service) GetPlayerStatus(userID int) (*Match, int, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// requeue puts players whose match could not be created back at the front
+// of their queue
+func (s *Service) requeue(ctx context.Context, key string, players ...Player) {
+	if err := s.store.Requeue(ctx, key, players); err != nil {
+		logger := logging.FromContext(ctx)
+		logger.Error().Err(err).Str("queue", key).Msg("Failed to put players back in the queue")
+	}
+}
 
+// GetPlayerStatus returns the match for a player if matched, or their position in queue
+func (s *Service) GetPlayerStatus(ctx context.Context, userID int) (*Match, int, error) {
 	// Check if player is in a match
-	if matchID, exists := s.playerMatches[userID]; exists {
-		if match, found := s.matches[matchID]; found {
-			return match, -1, nil
-		}
+	match, err := s.playerMatch(ctx, userID)
+	if err == nil {
+		return match, -1, nil
+	}
+	if !errors.Is(err, ErrMatchNotFound) {
+		return nil, -1, err
 	}
 
-	// Check if player is in queue and get position
-	for i, player := range s.queue {
-		if player.UserID == userID {
-			return nil, i, nil
-		}
+	// Check if player is in a queue and get position
+	_, position, err := s.store.QueuePosition(ctx, userID)
+	if errors.Is(err, ErrNotQueued) {
+		// Player is not in queue or match
+		return nil, -1, nil
+	}
+	if err != nil {
+		return nil, -1, err
 	}
 
-	// Player is not in queue or match
-	return nil, -1, nil
+	return nil, position, nil
 }
 
-// RemoveFromQueue removes a player from the queue
-func (s *Service) RemoveFromQueue(userID int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Check queue
-	for i, player := range s.queue {
-		if player.UserID == userID {
-			// Remove the player from the queue
-			s.queue = append(s.queue[:i], s.queue[i+1:]...)
-			return nil
-		}
+// playerMatch returns a player's active match
+func (s *Service) playerMatch(ctx context.Context, userID int) (*Match, error) {
+	matchID, err := s.store.MatchIDForPlayer(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
+	return s.store.GetMatch(ctx, matchID)
+}
 
-	// Check if player is in a match but not started
-	// We don't allow leaving once matched, so no implementation here
+// GetMatchByGameID returns the active match hosting the given engine game
+func (s *Service) GetMatchByGameID(ctx context.Context, gameID string) (*Match, error) {
+	matchID, err := s.store.MatchIDForGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.GetMatch(ctx, matchID)
+}
+
+// RemoveFromQueue removes a player from the queue. Players can't leave once
+// matched.
+func (s *Service) RemoveFromQueue(ctx context.Context, userID int) error {
+	key, position, err := s.store.Dequeue(ctx, userID)
+	if err != nil {
+		return err
+	}
 
-	return errors.New("user not found in the matchmaking queue")
+	s.publishQueuePositions(ctx, key, position)
+	return nil
 }
 
-// GetQueueStats returns the number of players in queue and active matches
-func (s *Service) GetQueueStats() (int, int) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetQueueStats returns the number of players in all queues and active
+// matches. Counts that can't be read from the store are reported as zero.
+func (s *Service) GetQueueStats(ctx context.Context) (int, int) {
+	logger := logging.FromContext(ctx)
+
+	queued, err := s.store.CountQueued(ctx)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to count queued players")
+	}
 
-	return len(s.queue), len(s.matches)
+	active, err := s.store.CountMatches(ctx)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to count active matches")
+	}
+
+	return queued, active
 }
 
-// RemoveMatch removes a match by ID and cleans up all associated player mappings
-func (s *Service) RemoveMatch(matchID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// RemoveMatch cancels a match by ID and cleans up all associated player mappings
+func (s *Service) RemoveMatch(ctx context.Context, matchID string) error {
+	_, err := s.updateMatch(ctx, matchID, func(match *Match) error {
+		return match.transition(StatusCancelled, "match removed")
+	})
+	return err
+}
 
-	// Find the match
-	match, exists := s.matches[matchID]
-	if !exists {
-		return errors.New("match not found")
+// ListMatches returns a snapshot of every active match, oldest first
+func (s *Service) ListMatches(ctx context.Context) ([]*Match, error) {
+	matches, err := s.store.ListMatches(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Remove player mappings
-	delete(s.playerMatches, match.Player1.UserID)
-	delete(s.playerMatches, match.Player2.UserID)
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+	})
+
+	return matches, nil
+}
+
+// CancelMatch cancels an active match without recording a result, notifies
+// both players with reason and deletes the game from its engine
+func (s *Service) CancelMatch(ctx context.Context, matchID, reason string) error {
+	match, err := s.updateMatch(ctx, matchID, func(match *Match) error {
+		return match.transition(StatusCancelled, reason)
+	})
+	if err != nil {
+		return err
+	}
 
-	// Remove the match itself
-	delete(s.matches, matchID)
+	if err := s.engineService.DeleteGame(ctx, match.Engine, match.GameID); err != nil {
+		return fmt.Errorf("match cancelled but failed to delete game %s from engine: %w", match.GameID, err)
+	}
 
 	return nil
 }
 
-// RemovePlayerFromMatch removes a player from their current match
+// RemovePlayerFromMatch cancels the current match of a player
 // This is useful when a player disconnects or a game ends
-func (s *Service) RemovePlayerFromMatch(userID int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
+func (s *Service) RemovePlayerFromMatch(ctx context.Context, userID int) error {
 	// Find the match ID for this player
-	matchID, exists := s.playerMatches[userID]
-	if !exists {
+	matchID, err := s.store.MatchIDForPlayer(ctx, userID)
+	if errors.Is(err, ErrMatchNotFound) {
 		return errors.New("player is not in any match")
 	}
+	if err != nil {
+		return err
+	}
 
-	// Get the match to find the other player
-	match, found := s.matches[matchID]
-	if !found {
-		// Clean up orphaned player mapping
-		delete(s.playerMatches, userID)
+	_, err = s.updateMatch(ctx, matchID, func(match *Match) error {
+		return match.transition(StatusCancelled, "player left match")
+	})
+	if errors.Is(err, ErrMatchNotFound) {
+		// The match ended in the meantime
 		return nil
 	}
-
-	// Remove both players from the match
-	delete(s.playerMatches, match.Player1.UserID)
-	delete(s.playerMatches, match.Player2.UserID)
-
-	// Remove the match
-	delete(s.matches, matchID)
-
-	return nil
+	return err
 }
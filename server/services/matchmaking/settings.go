@@ -0,0 +1,172 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/opening"
+)
+
+// GameSettings are the options a player queues with. Players are only paired
+// with others who chose the same settings.
+type GameSettings struct {
+	TimeControl  TimeControl `json:"timeControl"`            // Omit for an untimed game
+	OpeningSuite string      `json:"openingSuite,omitempty"` // Omit to start from the initial position
+	Variant      string      `json:"variant,omitempty"`      // Omit for standard chess
+
+	// Series names the scope an opening suite is rotated in, such as a
+	// series, tournament or regression run. Each series works through the
+	// suite from its first opening, and players are only paired within
+	// their series. Omit to share the pair's rotation across all their games.
+	Series string `json:"series,omitempty"`
+}
+
+// seriesPattern restricts series names to characters that are safe in
+// queue and store keys
+var seriesPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Key identifies the queue for these settings, e.g. "300+5",
+// "300+5/book", "300+5/book#run-12" or "chess960:300+5"
+func (g GameSettings) Key() string {
+	key := g.TimeControl.Key()
+	if g.OpeningSuite != "" {
+		key += "/" + g.OpeningSuite
+	}
+	if g.Series != "" {
+		key += "#" + g.Series
+	}
+	if g.Variant != model.VariantStandard {
+		key = g.Variant + ":" + key
+	}
+	return key
+}
+
+// normalize fills in defaults so equivalent settings share a queue
+func (g GameSettings) normalize() GameSettings {
+	if g.Variant == "" {
+		g.Variant = model.VariantStandard
+	}
+	return g
+}
+
+// validateSettings checks the settings a player queues with
+func (s *Service) validateSettings(settings GameSettings) error {
+	if err := settings.TimeControl.Validate(); err != nil {
+		return err
+	}
+	if !model.ValidVariant(settings.Variant) {
+		return fmt.Errorf("unsupported variant: %s", settings.Variant)
+	}
+	if settings.Variant == model.VariantChess960 && settings.OpeningSuite != "" {
+		return errors.New("opening suites cannot be used with chess960")
+	}
+	if settings.OpeningSuite != "" {
+		if _, err := s.openings.Suite(settings.OpeningSuite); err != nil {
+			return err
+		}
+	}
+	if settings.Series != "" {
+		if settings.OpeningSuite == "" {
+			return errors.New("a series requires an opening suite")
+		}
+		if !seriesPattern.MatchString(settings.Series) {
+			return errors.New("series names must be 1 to 64 letters, digits, '.', '_' or '-'")
+		}
+	}
+	return nil
+}
+
+// sideToMove returns the color to move in the match's starting position
+func (m *Match) sideToMove() string {
+	if m.Opening == nil {
+		return ColorWhite
+	}
+	return opening.SideToMove(m.Opening.FEN)
+}
+
+// OpeningRotation tracks how far a pair of players has got through a suite
+// within a series
+type OpeningRotation struct {
+	Played    int `json:"played"`    // Games the pair has started from the suite
+	LastWhite int `json:"lastWhite"` // User ID of white in the pair's previous game
+}
+
+// rotationKey identifies a pair of players' models within a suite and
+// series, regardless of who queued first
+func rotationKey(suite, series string, a, b Player) string {
+	if a.ModelID > b.ModelID || (a.ModelID == b.ModelID && a.UserID > b.UserID) {
+		a, b = b, a
+	}
+	key := fmt.Sprintf("%s:%d:%d:%d:%d", suite, a.UserID, a.ModelID, b.UserID, b.ModelID)
+	if series != "" {
+		key += "#" + series
+	}
+	return key
+}
+
+// pairing is the color assignment and starting position chosen for a match
+type pairing struct {
+	white, black Player
+	opening      *opening.Opening
+
+	// commit advances the pair's place in the opening suite. It is only
+	// called once the engine has accepted the game.
+	commit func(ctx context.Context) error
+}
+
+// pair assigns colors and picks the starting position for two players.
+// Chess960 games start from a random position. With an opening suite, each
+// opening is played twice with colors reversed before the pair moves on to
+// the next one.
+func (s *Service) pair(ctx context.Context, settings GameSettings, a, b Player) (*pairing, error) {
+	p := &pairing{white: a, black: b, commit: func(context.Context) error { return nil }}
+	if rand.Intn(2) == 1 {
+		p.white, p.black = b, a
+	}
+
+	if settings.Variant == model.VariantChess960 {
+		position, err := opening.Chess960(rand.Intn(opening.Chess960Positions))
+		if err != nil {
+			return nil, err
+		}
+		p.opening = &position
+		return p, nil
+	}
+
+	if settings.OpeningSuite == "" {
+		return p, nil
+	}
+
+	suite, err := s.openings.Suite(settings.OpeningSuite)
+	if err != nil {
+		return nil, err
+	}
+
+	key := rotationKey(suite.Name, settings.Series, a, b)
+	rotation, err := s.store.OpeningRotation(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The second game of each opening reverses the first game's colors
+	if rotation.Played%2 == 1 {
+		p.white, p.black = a, b
+		if a.UserID == rotation.LastWhite {
+			p.white, p.black = b, a
+		}
+	}
+
+	p.opening = &suite.Openings[(rotation.Played/2)%len(suite.Openings)]
+	p.commit = func(ctx context.Context) error {
+		return s.store.SaveOpeningRotation(ctx, key, OpeningRotation{
+			Played:    rotation.Played + 1,
+			LastWhite: p.white.UserID,
+		})
+	}
+
+	return p, nil
+}
@@ -0,0 +1,158 @@
+package matchmaking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// Store backends
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// ErrAlreadyQueued is returned when a player joins a queue while already
+// waiting in one
+var ErrAlreadyQueued = errors.New("user is already in the matchmaking queue")
+
+// ErrAlreadyInMatch is returned when a player joins a queue while they have
+// an active match
+var ErrAlreadyInMatch = errors.New("user is already in a match")
+
+// ErrNotQueued is returned when a player is not waiting in any queue
+var ErrNotQueued = errors.New("user not found in the matchmaking queue")
+
+// ErrPairingExpired is returned when a match is created for players whose
+// pairing lease ran out, releasing them to queue again
+var ErrPairingExpired = errors.New("pairing lease expired")
+
+// StoreInterface holds the matchmaking state: the queues, active matches,
+// progress through opening suites and the events sent to players.
+// Implementations must be safe for concurrent use; the Redis store is also
+// shared by every server instance pointed at it.
+type StoreInterface interface {
+	// Enqueue adds a player to the end of the queue for key. If the queue
+	// then holds two players or more, the first two are taken off it and
+	// returned to be paired. Players taken for pairing stay registered as
+	// queued, at position 0, until they are put in a match or requeued.
+	// Fails with ErrAlreadyQueued or ErrAlreadyInMatch.
+	Enqueue(ctx context.Context, key string, player Player) (int, []Player, error)
+
+	// Requeue puts players whose pairing failed back at the front of the
+	// queue for key, in order
+	Requeue(ctx context.Context, key string, players []Player) error
+
+	// Dequeue removes a waiting player from their queue and returns the
+	// queue's key and the position they held. Fails with ErrNotQueued.
+	Dequeue(ctx context.Context, userID int) (string, int, error)
+
+	// QueuePosition returns the key and position of a queued player. Fails
+	// with ErrNotQueued.
+	QueuePosition(ctx context.Context, userID int) (string, int, error)
+
+	// QueuedPlayers returns the user IDs waiting in the queue for key, in
+	// order
+	QueuedPlayers(ctx context.Context, key string) ([]int, error)
+
+	// CountQueued returns the number of players waiting across all queues
+	CountQueued(ctx context.Context) (int, error)
+
+	// CreateMatch stores a new match and moves both its players out of
+	// their queue and into it. Fails with ErrPairingExpired if a player was
+	// released from pairing in the meantime.
+	CreateMatch(ctx context.Context, match *Match) error
+
+	// UpdateMatch applies update to a match atomically and stores the
+	// result. A match that reaches a terminal status is removed, releasing
+	// its players. If update fails, the match is left unchanged and the
+	// error is returned. Fails with ErrMatchNotFound.
+	UpdateMatch(ctx context.Context, matchID string, update func(*Match) error) (*Match, error)
+
+	// GetMatch returns an active match. Fails with ErrMatchNotFound.
+	GetMatch(ctx context.Context, matchID string) (*Match, error)
+
+	// MatchIDForPlayer returns the ID of a player's active match. Fails with
+	// ErrMatchNotFound.
+	MatchIDForPlayer(ctx context.Context, userID int) (string, error)
+
+	// MatchIDForGame returns the ID of the active match hosting an engine
+	// game. Fails with ErrMatchNotFound.
+	MatchIDForGame(ctx context.Context, gameID string) (string, error)
+
+	// ListMatches returns every active match in no particular order
+	ListMatches(ctx context.Context) ([]*Match, error)
+
+	// CountMatches returns the number of active matches
+	CountMatches(ctx context.Context) (int, error)
+
+	// OpeningRotation returns how far a pair of players has got through an
+	// opening suite; the zero value if they haven't started it
+	OpeningRotation(ctx context.Context, key string) (OpeningRotation, error)
+
+	// SaveOpeningRotation records a pair's progress through an opening suite
+	SaveOpeningRotation(ctx context.Context, key string, rotation OpeningRotation) error
+
+	// PublishEvent sends an event to the subscribers of userID on every
+	// server instance
+	PublishEvent(ctx context.Context, userID int, event Event) error
+
+	// ListenEvents passes every published event to deliver until ctx is
+	// cancelled
+	ListenEvents(ctx context.Context, deliver func(userID int, event Event)) error
+
+	// Restore reloads the active matches kept from before a restart and
+	// returns the IDs of those it reloaded
+	Restore(ctx context.Context) ([]string, error)
+
+	// QueueKeys returns the keys of the queues holding waiting players
+	QueueKeys(ctx context.Context) ([]string, error)
+
+	// Shared reports whether the queues are shared with other server
+	// instances and outlive this one
+	Shared() bool
+}
+
+// MatchPersisterInterface saves active matches outside the process, so the
+// memory store can restore them after a restart
+type MatchPersisterInterface interface {
+	SaveActiveMatch(ctx context.Context, match *model.ActiveMatch) error
+	DeleteActiveMatch(ctx context.Context, id string) error
+	ListActiveMatches(ctx context.Context) ([]*model.ActiveMatch, error)
+}
+
+// storedMatch is how a match is kept outside the process, including the
+// adjudication state that isn't part of its JSON form
+type storedMatch struct {
+	*Match
+	Imbalance storedStreak `json:"imbalance"`
+}
+
+type storedStreak struct {
+	Leader string `json:"leader,omitempty"`
+	Plies  int    `json:"plies,omitempty"`
+}
+
+func encodeMatch(match *Match) ([]byte, error) {
+	data, err := json.Marshal(storedMatch{
+		Match:     match,
+		Imbalance: storedStreak{Leader: match.imbalance.leader, Plies: match.imbalance.plies},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode match: %w", err)
+	}
+	return data, nil
+}
+
+func decodeMatch(data []byte) (*Match, error) {
+	stored := storedMatch{Match: &Match{}}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode match: %w", err)
+	}
+
+	stored.Match.imbalance = imbalanceStreak{leader: stored.Imbalance.Leader, plies: stored.Imbalance.Plies}
+	return stored.Match, nil
+}
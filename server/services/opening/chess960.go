@@ -0,0 +1,72 @@
+package opening
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Chess960Positions is the number of Chess960 starting positions
+const Chess960Positions = 960
+
+// knightPlacements maps the knight digit of a Scharnagl number to the two
+// empty squares the knights take once the bishops and queen are placed
+var knightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960 returns the starting position with the given Scharnagl number
+// from 0 to 959; 518 is the standard initial position. The engine doesn't
+// implement Chess960 castling, so positions are generated without castling
+// rights.
+func Chess960(id int) (Opening, error) {
+	if id < 0 || id >= Chess960Positions {
+		return Opening{}, fmt.Errorf("invalid Chess960 position %d", id)
+	}
+
+	var rank [8]byte
+	n := id
+
+	// Light-squared bishop on b, d, f or h, then dark-squared on a, c, e or g
+	rank[n%4*2+1] = 'b'
+	n /= 4
+	rank[n%4*2] = 'b'
+	n /= 4
+
+	placeNth(&rank, n%6, 'q')
+	n /= 6
+
+	// Both knights go on the empty squares before the later one is counted
+	knights := knightPlacements[n]
+	second := nthEmpty(&rank, knights[1])
+	rank[nthEmpty(&rank, knights[0])] = 'n'
+	rank[second] = 'n'
+
+	// The king always stands between the rooks
+	placeNth(&rank, 0, 'r')
+	placeNth(&rank, 0, 'k')
+	placeNth(&rank, 0, 'r')
+
+	black := string(rank[:])
+	fen := fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w - - 0 1", black, strings.ToUpper(black))
+
+	return Opening{Name: fmt.Sprintf("Chess960 #%d", id), FEN: fen}, nil
+}
+
+// placeNth puts piece on the nth empty square of rank
+func placeNth(rank *[8]byte, n int, piece byte) {
+	rank[nthEmpty(rank, n)] = piece
+}
+
+// nthEmpty returns the file of the nth empty square of rank
+func nthEmpty(rank *[8]byte, n int) int {
+	for file, piece := range rank {
+		if piece != 0 {
+			continue
+		}
+		if n == 0 {
+			return file
+		}
+		n--
+	}
+	panic("opening: rank has too few empty squares")
+}
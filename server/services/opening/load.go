@@ -0,0 +1,255 @@
+package opening
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pgnTag matches a PGN tag pair such as [FEN "..."]
+var pgnTag = regexp.MustCompile(`^\[(\w+)\s+"((?:[^"\\]|\\.)*)"\]$`)
+
+// LoadDir loads every .epd and .pgn file in dir as a suite named after the
+// file. An empty dir loads no suites.
+func LoadDir(dir string) ([]*Suite, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read opening suites: %w", err)
+	}
+
+	var suites []*Suite
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".epd" && ext != ".pgn" {
+			continue
+		}
+
+		suite, err := loadFile(filepath.Join(dir, entry.Name()), ext)
+		if err != nil {
+			return nil, err
+		}
+		suites = append(suites, suite)
+	}
+
+	return suites, nil
+}
+
+// loadFile parses a single suite file
+func loadFile(path, ext string) (*Suite, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open opening suite: %w", err)
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var openings []Opening
+	if ext == ".epd" {
+		openings, err = parseEPD(f, name)
+	} else {
+		openings, err = parsePGN(f, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load opening suite %s: %w", path, err)
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("opening suite %s has no positions", path)
+	}
+
+	return &Suite{Name: name, Openings: openings}, nil
+}
+
+// parseEPD reads one position per line. Each line holds the first four FEN
+// fields followed by optional operations; "id" names the opening and
+// "hmvc"/"fmvn" set the move counters.
+func parseEPD(r io.Reader, suite string) ([]Opening, error) {
+	var openings []Opening
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected at least 4 fields", lineNo)
+		}
+
+		name := fmt.Sprintf("%s #%d", suite, len(openings)+1)
+		halfmove, fullmove := "0", "1"
+
+		rest := strings.TrimSpace(strings.Join(fields[4:], " "))
+		// Some files append the move counters as in a full FEN
+		if counters := strings.Fields(rest); len(counters) >= 2 && isNumber(counters[0]) && isNumber(counters[1]) {
+			halfmove, fullmove = counters[0], counters[1]
+			rest = strings.TrimSpace(strings.Join(counters[2:], " "))
+		}
+
+		for _, op := range strings.Split(rest, ";") {
+			opcode, operand, _ := strings.Cut(strings.TrimSpace(op), " ")
+			operand = strings.Trim(strings.TrimSpace(operand), `"`)
+			switch opcode {
+			case "id":
+				name = operand
+			case "hmvc":
+				halfmove = operand
+			case "fmvn":
+				fullmove = operand
+			}
+		}
+
+		fen := strings.Join(append(fields[:4:4], halfmove, fullmove), " ")
+		if err := ValidateFEN(fen); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		openings = append(openings, Opening{Name: name, FEN: fen})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return openings, nil
+}
+
+// parsePGN reads the starting position of every game from its FEN tag.
+// Games are named by their Opening and Variation tags, falling back to ECO
+// or Event. Move text is not replayed, so every game must have a FEN tag.
+func parsePGN(r io.Reader, suite string) ([]Opening, error) {
+	var openings []Opening
+	var tags map[string]string
+
+	flush := func() error {
+		if tags == nil {
+			return nil
+		}
+		defer func() { tags = nil }()
+
+		fen, ok := tags["FEN"]
+		if !ok {
+			return fmt.Errorf("game %d has no FEN tag", len(openings)+1)
+		}
+		if err := ValidateFEN(fen); err != nil {
+			return fmt.Errorf("game %d: %w", len(openings)+1, err)
+		}
+
+		name := tags["Opening"]
+		if variation := tags["Variation"]; name != "" && variation != "" {
+			name += ": " + variation
+		}
+		for _, fallback := range []string{tags["ECO"], tags["Event"], fmt.Sprintf("%s #%d", suite, len(openings)+1)} {
+			if name == "" || name == "?" {
+				name = fallback
+			}
+		}
+
+		openings = append(openings, Opening{Name: name, FEN: fen})
+		return nil
+	}
+
+	inMoves := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		match := pgnTag.FindStringSubmatch(line)
+		if match == nil {
+			inMoves = true
+			continue
+		}
+
+		// A tag after move text starts the next game
+		if inMoves || tags == nil {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			tags = make(map[string]string)
+			inMoves = false
+		}
+		tags[match[1]] = strings.ReplaceAll(match[2], `\"`, `"`)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return openings, nil
+}
+
+// ValidateFEN checks that fen has the structure of a FEN record. It does not
+// check that the position is legal; the engine rejects those when the game
+// is created.
+func ValidateFEN(fen string) error {
+	fields := strings.Fields(fen)
+	if len(fields) != 6 {
+		return fmt.Errorf("invalid FEN %q: expected 6 fields", fen)
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return fmt.Errorf("invalid FEN %q: expected 8 ranks", fen)
+	}
+	for _, rank := range ranks {
+		files := 0
+		for _, c := range rank {
+			switch {
+			case c >= '1' && c <= '8':
+				files += int(c - '0')
+			case strings.ContainsRune("pnbrqkPNBRQK", c):
+				files++
+			default:
+				return fmt.Errorf("invalid FEN %q: unexpected %q", fen, c)
+			}
+		}
+		if files != 8 {
+			return fmt.Errorf("invalid FEN %q: rank %q does not have 8 files", fen, rank)
+		}
+	}
+
+	if fields[1] != "w" && fields[1] != "b" {
+		return fmt.Errorf("invalid FEN %q: side to move must be w or b", fen)
+	}
+	if !isNumber(fields[4]) || !isNumber(fields[5]) {
+		return fmt.Errorf("invalid FEN %q: move counters must be numbers", fen)
+	}
+
+	return nil
+}
+
+// SideToMove returns "white" or "black" for a FEN record, defaulting to
+// white if fen is empty
+func SideToMove(fen string) string {
+	if fields := strings.Fields(fen); len(fields) > 1 && fields[1] == "b" {
+		return "black"
+	}
+	return "white"
+}
+
+// isNumber reports whether s is a non-negative integer
+func isNumber(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0
+}
@@ -0,0 +1,67 @@
+package opening
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Opening is a starting position games can be played from
+type Opening struct {
+	Name string `json:"name"`
+	FEN  string `json:"fen"`
+}
+
+// Suite is a named, ordered set of openings loaded from one EPD or PGN file
+type Suite struct {
+	Name     string    `json:"name"`
+	Openings []Opening `json:"openings"`
+}
+
+// ServiceInterface defines the contract for the opening suite service
+type ServiceInterface interface {
+	// Suites returns every loaded suite ordered by name
+	Suites() []*Suite
+
+	// Suite looks up a suite by name
+	Suite(name string) (*Suite, error)
+}
+
+// Service serves the opening suites loaded at startup
+type Service struct {
+	suites map[string]*Suite
+}
+
+// NewService creates a new opening service serving suites
+func NewService(suites []*Suite) ServiceInterface {
+	byName := make(map[string]*Suite, len(suites))
+	for _, suite := range suites {
+		byName[suite.Name] = suite
+	}
+
+	return &Service{
+		suites: byName,
+	}
+}
+
+// Suites returns every loaded suite ordered by name
+func (s *Service) Suites() []*Suite {
+	suites := make([]*Suite, 0, len(s.suites))
+	for _, suite := range s.suites {
+		suites = append(suites, suite)
+	}
+
+	sort.Slice(suites, func(i, j int) bool {
+		return suites[i].Name < suites[j].Name
+	})
+
+	return suites
+}
+
+// Suite looks up a suite by name
+func (s *Service) Suite(name string) (*Suite, error) {
+	suite, ok := s.suites[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown opening suite %q", name)
+	}
+	return suite, nil
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled are forgotten
+const sweepInterval = time.Minute
+
+// ServiceInterface defines the contract for a request rate limiter
+type ServiceInterface interface {
+	// Allow takes a token from key's bucket. When the bucket is empty it
+	// returns false and how long until the next token is added.
+	Allow(key string) (bool, time.Duration)
+}
+
+// Policy is a token bucket: each key may make Burst requests at once, and
+// its bucket refills at PerMinute tokens a minute
+type Policy struct {
+	PerMinute int // Sustained requests per minute
+	Burst     int // Requests allowed at once from a full bucket
+}
+
+// bucket is the token count of one key as of its last update
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Service limits requests per key. Buckets are kept in process, so each
+// server instance limits the requests it receives on its own.
+type Service struct {
+	mu        sync.Mutex
+	policy    Policy
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewService creates a rate limiter enforcing policy
+func NewService(policy Policy) ServiceInterface {
+	return &Service{
+		policy:  policy,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from key's bucket, or reports how long until one is
+// available
+func (s *Service) Allow(key string) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(s.policy.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = s.refill(b, now)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	missing := 1 - b.tokens
+	return false, time.Duration(math.Ceil(missing / s.rate() * float64(time.Second)))
+}
+
+// refill returns b's token count at now
+func (s *Service) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.updated).Seconds()*s.rate()
+	return math.Min(tokens, float64(s.policy.Burst))
+}
+
+// rate returns how many tokens are added to a bucket per second
+func (s *Service) rate() float64 {
+	return float64(s.policy.PerMinute) / 60
+}
+
+// sweep forgets buckets that have refilled, which behave exactly like a
+// new bucket, so idle keys don't accumulate. It runs at most once per
+// sweepInterval.
+func (s *Service) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if s.refill(b, now) >= float64(s.policy.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
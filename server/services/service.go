@@ -1,10 +1,20 @@
 package services
 
 import (
+	"github.com/ajlaz/checkmAIt/server/config"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/apikeys"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/games"
+	dblimiter "github.com/ajlaz/checkmAIt/server/db/store/postgres/limiter"
 	"github.com/ajlaz/checkmAIt/server/db/store/postgres/models"
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/sessions"
 	"github.com/ajlaz/checkmAIt/server/db/store/postgres/users"
+	"github.com/ajlaz/checkmAIt/server/services/api_key"
 	"github.com/ajlaz/checkmAIt/server/services/engine"
+	"github.com/ajlaz/checkmAIt/server/services/game"
+	"github.com/ajlaz/checkmAIt/server/services/limiter"
+	"github.com/ajlaz/checkmAIt/server/services/mailer"
 	"github.com/ajlaz/checkmAIt/server/services/matchmaking"
+	"github.com/ajlaz/checkmAIt/server/services/opening"
 	"github.com/ajlaz/checkmAIt/server/services/user"
 	"github.com/ajlaz/checkmAIt/server/services/user_model"
 )
@@ -15,17 +25,81 @@ type Services struct {
 	MatchmakingService matchmaking.ServiceInterface
 	ModelService       user_model.ServiceInterface
 	EngineService      engine.ServiceInterface
+	GameService        game.ServiceInterface
+	OpeningService     opening.ServiceInterface
+	APIKeyService      api_key.ServiceInterface
+
+	// Failed login limits per account and per client IP
+	AccountLimiter limiter.ServiceInterface
+	IPLimiter      limiter.ServiceInterface
 }
 
-func NewServices(userStore users.StoreInterface, modelStore models.StoreInterface, engineURL string) *Services {
-	userService := user.NewService(userStore)
-	engineService := engine.NewService(engineURL)
-	matchmakingService := matchmaking.NewService(engineService)
-	modelService := user_model.NewService(modelStore)
+func NewServices(cfg *config.Config, userStore users.StoreInterface, modelStore models.StoreInterface, gameStore games.StoreInterface, sessionStore sessions.StoreInterface, apiKeyStore apikeys.StoreInterface, limiterStore dblimiter.StoreInterface, matchmakingStore matchmaking.StoreInterface) (*Services, error) {
+	suites, err := opening.LoadDir(cfg.Openings.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	userService := user.NewService(userStore, sessionStore, mail, user.Config{
+		RefreshTokenTTL:      cfg.Auth.RefreshTokenTTL,
+		VerificationTokenTTL: cfg.Auth.VerificationTokenTTL,
+		PasswordResetTTL:     cfg.Auth.PasswordResetTTL,
+		AppURL:               cfg.Mail.AppURL,
+	})
+	engineService := engine.NewService(cfg.Engine.Endpoints(), cfg.Engine.RequestTimeout)
+	modelService := user_model.NewService(modelStore, user_model.Quotas{
+		MaxModels:    cfg.Quotas.MaxModelsPerUser,
+		MaxCodeBytes: cfg.Quotas.MaxCodeBytes,
+	})
+	gameService := game.NewService(gameStore, modelService, cfg.Quotas.MaxGamesPerDay)
+	openingService := opening.NewService(suites)
+	apiKeyService := api_key.NewService(apiKeyStore, userStore)
+
+	limits := cfg.LoginLimit
+	if limits.Backend == "memory" {
+		limiterStore = limiter.NewMemoryStore(limits.Window)
+	}
+	accountLimiter := limiter.NewService("login_account", limiterStore, limiter.Policy{
+		FreeAttempts:    limits.AccountFreeAttempts,
+		BackoffBase:     limits.BackoffBase,
+		BackoffMax:      limits.BackoffMax,
+		LockoutAttempts: limits.AccountLockoutAttempts,
+		LockoutDuration: limits.LockoutDuration,
+		Window:          limits.Window,
+	})
+	ipLimiter := limiter.NewService("login_ip", limiterStore, limiter.Policy{
+		FreeAttempts:    limits.IPFreeAttempts,
+		BackoffBase:     limits.BackoffBase,
+		BackoffMax:      limits.BackoffMax,
+		LockoutAttempts: limits.IPLockoutAttempts,
+		LockoutDuration: limits.LockoutDuration,
+		Window:          limits.Window,
+	})
+	matchmakingService := matchmaking.NewService(matchmakingStore, engineService, gameService, openingService, matchmaking.LifecycleConfig{
+		ConnectTimeout: cfg.Matchmaking.ConnectTimeout,
+		GameTimeout:    cfg.Matchmaking.GameTimeout,
+		ReapInterval:   cfg.Matchmaking.ReapInterval,
+		ReconnectGrace: cfg.Matchmaking.ReconnectGrace,
+	}, matchmaking.AdjudicationConfig{
+		MoveCap:              cfg.Adjudication.MoveCap,
+		InsufficientMaterial: cfg.Adjudication.InsufficientMaterial,
+		MaterialThreshold:    cfg.Adjudication.MaterialThreshold,
+		MaterialPlies:        cfg.Adjudication.MaterialPlies,
+	})
 	return &Services{
 		UserService:        userService,
 		MatchmakingService: matchmakingService,
 		EngineService:      engineService,
 		ModelService:       modelService,
-	}
+		GameService:        gameService,
+		OpeningService:     openingService,
+		APIKeyService:      apiKeyService,
+		AccountLimiter:     accountLimiter,
+		IPLimiter:          ipLimiter,
+	}, nil
 }
@@ -0,0 +1,154 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/users"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/secrets"
+	"github.com/ajlaz/checkmAIt/server/services/mailer"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidToken is returned when a verification or reset token is
+// unknown, expired or already used
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// ErrEmailAlreadyVerified is returned when asking to verify an address that
+// has already been verified
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+
+// SendVerificationEmail emails a user a link to verify their address.
+// Links sent earlier stop working.
+func (s *Service) SendVerificationEmail(ctx context.Context, userID int) error {
+	user, err := s.userStore.GetUserByID(ctx, strconv.Itoa(userID))
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueUserToken(ctx, userID, model.TokenPurposeVerifyEmail, s.config.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.link("/verify-email", token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your checkmAIt email address",
+		Body: fmt.Sprintf("Hi %s,\r\n\r\nConfirm your email address by opening this link within %s:\r\n\r\n%s\r\n\r\n"+
+			"If you didn't create a checkmAIt account, you can ignore this email.\r\n",
+			user.Username, s.config.VerificationTokenTTL, link),
+	})
+}
+
+// VerifyEmail marks the address a verification token was sent to as
+// verified
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	userToken, err := s.consumeUserToken(ctx, token, model.TokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	return s.userStore.SetEmailVerified(ctx, userToken.UserID)
+}
+
+// RequestPasswordReset emails a password reset link to the user with email.
+// Unknown and banned addresses are ignored without an error so callers
+// can't probe which accounts exist.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userStore.GetUserByEmail(ctx, email)
+	if err != nil || user.Banned() {
+		return nil
+	}
+
+	token, err := s.issueUserToken(ctx, int(user.ID), model.TokenPurposeResetPassword, s.config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.link("/reset-password", token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your checkmAIt password",
+		Body: fmt.Sprintf("Hi %s,\r\n\r\nChoose a new password by opening this link within %s:\r\n\r\n%s\r\n\r\n"+
+			"If you didn't ask to reset your password, you can ignore this email.\r\n",
+			user.Username, s.config.PasswordResetTTL, link),
+	})
+}
+
+// ResetPassword sets a new password using a reset token. Every session of
+// the user is revoked, logging out anyone who knew the old password.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	userToken, err := s.consumeUserToken(ctx, token, model.TokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userStore.SetPasswordHash(ctx, userToken.UserID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	// The reset link proves control of the address
+	if err := s.userStore.SetEmailVerified(ctx, userToken.UserID); err != nil {
+		return err
+	}
+
+	return s.sessionStore.RevokeUserSessions(ctx, userToken.UserID, "")
+}
+
+// issueUserToken replaces a user's outstanding tokens for purpose with a new
+// one valid for ttl
+func (s *Service) issueUserToken(ctx context.Context, userID int, purpose string, ttl time.Duration) (string, error) {
+	if err := s.userStore.InvalidateUserTokens(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.userStore.CreateUserToken(ctx, &model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: secrets.Hash(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeUserToken uses up a token, failing with ErrInvalidToken if it can't
+// be used
+func (s *Service) consumeUserToken(ctx context.Context, token, purpose string) (*model.UserToken, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	userToken, err := s.userStore.ConsumeUserToken(ctx, secrets.Hash(token), purpose)
+	if errors.Is(err, users.ErrUserTokenNotFound) {
+		return nil, ErrInvalidToken
+	}
+
+	return userToken, err
+}
+
+// link builds a frontend URL carrying token
+func (s *Service) link(path, token string) string {
+	return s.config.AppURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,48 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+)
+
+// ErrUserBanned is returned when a banned user tries to log in or refresh
+// their session
+var ErrUserBanned = errors.New("user is banned")
+
+// ListUsers retrieves every user
+func (s *Service) ListUsers(ctx context.Context) ([]*model.User, error) {
+	return s.userStore.ListUsers(ctx)
+}
+
+// SetUserRole changes a user's role. The user's sessions are revoked so the
+// new role takes effect on their next login rather than when their current
+// access token expires.
+func (s *Service) SetUserRole(ctx context.Context, userID int, role string) error {
+	if !model.ValidRole(role) {
+		return fmt.Errorf("unknown role: %s", role)
+	}
+
+	if err := s.userStore.SetUserRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	return s.sessionStore.RevokeUserSessions(ctx, userID, "")
+}
+
+// BanUser bans a user and revokes their sessions, logging them out
+// everywhere
+func (s *Service) BanUser(ctx context.Context, userID int) error {
+	if err := s.userStore.SetUserBanned(ctx, userID, true); err != nil {
+		return err
+	}
+
+	return s.sessionStore.RevokeUserSessions(ctx, userID, "")
+}
+
+// UnbanUser lifts a user's ban
+func (s *Service) UnbanUser(ctx context.Context, userID int) error {
+	return s.userStore.SetUserBanned(ctx, userID, false)
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 
 	"github.com/ajlaz/checkmAIt/server/model"
@@ -8,9 +9,13 @@ import (
 )
 
 // CreateUser creates a new user with the provided credentials
-func (s *Service) CreateUser(username, email, password string) (*model.User, error) {
+func (s *Service) CreateUser(ctx context.Context, username, email, password string) (*model.User, error) {
+	if model.ReservedUsername(username) {
+		return nil, ErrReservedUsername
+	}
+
 	// Check if user with email already exists
-	existingUser, err := s.userStore.GetUserByEmail(email)
+	existingUser, err := s.userStore.GetUserByEmail(ctx, email)
 	if err == nil && existingUser != nil {
 		return nil, errors.New("email already in use")
 	}
@@ -22,7 +27,7 @@ func (s *Service) CreateUser(username, email, password string) (*model.User, err
 	}
 
 	// Create user
-	user, err := s.userStore.CreateUser(&model.User{
+	user, err := s.userStore.CreateUser(ctx, &model.User{
 		Username: username,
 		Email:    email,
 		Password: string(hashedPassword),
@@ -32,9 +37,9 @@ func (s *Service) CreateUser(username, email, password string) (*model.User, err
 }
 
 // AuthenticateUser verifies user credentials and returns the user if valid
-func (s *Service) AuthenticateUser(email, password string) (*model.User, error) {
+func (s *Service) AuthenticateUser(ctx context.Context, email, password string) (*model.User, error) {
 	// Get user by email
-	user, err := s.userStore.GetUserByEmail(email)
+	user, err := s.userStore.GetUserByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -45,5 +50,9 @@ func (s *Service) AuthenticateUser(email, password string) (*model.User, error)
 		return nil, errors.New("invalid password")
 	}
 
+	if user.Banned() {
+		return nil, ErrUserBanned
+	}
+
 	return user, nil
 }
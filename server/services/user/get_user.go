@@ -1,8 +1,12 @@
 package user
 
-import "github.com/ajlaz/checkmAIt/server/model"
+import (
+	"context"
 
-func (s *Service) GetUserByID(id string) (*model.User, error) {
+	"github.com/ajlaz/checkmAIt/server/model"
+)
 
-	return s.userStore.GetUserByID(id)
+func (s *Service) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+
+	return s.userStore.GetUserByID(ctx, id)
 }
@@ -0,0 +1,95 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ajlaz/checkmAIt/server/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrWrongPassword is returned when the current password given to confirm
+// an account change is incorrect
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+// ErrReservedUsername is returned when a user picks a username the server
+// keeps for itself, such as those given to deleted accounts
+var ErrReservedUsername = errors.New("username is reserved")
+
+// ProfileUpdate holds the account changes a user asks for. Empty fields are
+// left unchanged.
+type ProfileUpdate struct {
+	Username        string
+	Email           string
+	NewPassword     string
+	CurrentPassword string // Required for every change
+}
+
+// UpdateProfile changes a user's username, email or password after checking
+// their current password. A new email address has to be verified again. A
+// new password logs out every session except sessionID.
+func (s *Service) UpdateProfile(ctx context.Context, userID int, sessionID string, update ProfileUpdate) (*model.User, error) {
+	username := strings.TrimSpace(update.Username)
+	if model.ReservedUsername(username) {
+		return nil, ErrReservedUsername
+	}
+
+	user, err := s.checkPassword(ctx, userID, update.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if username != "" {
+		user.Username = username
+	}
+	if email := strings.TrimSpace(update.Email); email != "" && !strings.EqualFold(email, user.Email) {
+		user.Email = email
+		user.EmailVerifiedAt = nil
+	}
+
+	updated, err := s.userStore.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.NewPassword != "" {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(update.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.userStore.SetPasswordHash(ctx, userID, string(hashedPassword)); err != nil {
+			return nil, err
+		}
+		if err := s.sessionStore.RevokeUserSessions(ctx, userID, sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, nil
+}
+
+// DeleteAccount anonymizes a user's account after checking their password.
+// Their games are kept; their models, API keys and sessions are removed.
+func (s *Service) DeleteAccount(ctx context.Context, userID int, password string) error {
+	if _, err := s.checkPassword(ctx, userID, password); err != nil {
+		return err
+	}
+
+	return s.userStore.AnonymizeUser(ctx, userID)
+}
+
+// checkPassword loads a user and verifies their password
+func (s *Service) checkPassword(ctx context.Context, userID int, password string) (*model.User, error) {
+	user, err := s.userStore.GetUserByID(ctx, strconv.Itoa(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrWrongPassword
+	}
+
+	return user, nil
+}
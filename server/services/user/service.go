@@ -1,22 +1,59 @@
 package user
 
 import (
+	"context"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/sessions"
 	"github.com/ajlaz/checkmAIt/server/db/store/postgres/users"
 	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/services/mailer"
 )
 
 type ServiceInterface interface {
-	GetUserByID(id string) (*model.User, error)
-	CreateUser(username, email, password string) (*model.User, error)
-	AuthenticateUser(email, password string) (*model.User, error)
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	CreateUser(ctx context.Context, username, email, password string) (*model.User, error)
+	AuthenticateUser(ctx context.Context, email, password string) (*model.User, error)
+
+	StartSession(ctx context.Context, user *model.User) (*Session, error)
+	RefreshSession(ctx context.Context, refreshToken string) (*Session, error)
+	EndSession(ctx context.Context, refreshToken string) error
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+
+	SendVerificationEmail(ctx context.Context, userID int) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+
+	UpdateProfile(ctx context.Context, userID int, sessionID string, update ProfileUpdate) (*model.User, error)
+	DeleteAccount(ctx context.Context, userID int, password string) error
+
+	ListUsers(ctx context.Context) ([]*model.User, error)
+	SetUserRole(ctx context.Context, userID int, role string) error
+	BanUser(ctx context.Context, userID int) error
+	UnbanUser(ctx context.Context, userID int) error
+}
+
+// Config holds the token lifetimes and links used by the user service
+type Config struct {
+	RefreshTokenTTL      time.Duration // Lifetime of each refresh token
+	VerificationTokenTTL time.Duration // Lifetime of email verification links
+	PasswordResetTTL     time.Duration // Lifetime of password reset links
+	AppURL               string        // Base URL of the frontend the emailed links open
 }
 
 type Service struct {
-	userStore users.StoreInterface
+	userStore    users.StoreInterface
+	sessionStore sessions.StoreInterface
+	mailer       mailer.Mailer
+	config       Config
 }
 
-func NewService(userStore users.StoreInterface) ServiceInterface {
+func NewService(userStore users.StoreInterface, sessionStore sessions.StoreInterface, mailer mailer.Mailer, config Config) ServiceInterface {
 	return &Service{
-		userStore: userStore,
+		userStore:    userStore,
+		sessionStore: sessionStore,
+		mailer:       mailer,
+		config:       config,
 	}
 }
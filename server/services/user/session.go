@@ -0,0 +1,171 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/sessions"
+	"github.com/ajlaz/checkmAIt/server/model"
+	"github.com/ajlaz/checkmAIt/server/secrets"
+)
+
+// ErrInvalidRefreshToken is returned when a refresh token is unknown,
+// expired, already used or belongs to a revoked session
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// Session is the result of logging in or refreshing: the session that access
+// tokens are issued under and the refresh token to use next
+type Session struct {
+	ID           string
+	User         *model.User
+	RefreshToken string
+}
+
+// StartSession begins a new login session for user
+func (s *Service) StartSession(ctx context.Context, user *model.User) (*Session, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sessionStore.CreateSession(ctx, &model.Session{
+		ID:     sessionID,
+		UserID: int(user.ID),
+	}); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{ID: sessionID, User: user, RefreshToken: refreshToken}, nil
+}
+
+// RefreshSession exchanges a refresh token for a new one in the same session.
+// Refresh tokens are single use; presenting one a second time means it has
+// leaked, so the whole session is revoked.
+func (s *Service) RefreshSession(ctx context.Context, refreshToken string) (*Session, error) {
+	token, session, err := s.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.UsedAt != nil {
+		return nil, s.revokeReusedSession(ctx, session.ID)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Claim the token atomically so concurrent refreshes can't both succeed
+	claimed, err := s.sessionStore.MarkRefreshTokenUsed(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, s.revokeReusedSession(ctx, session.ID)
+	}
+
+	user, err := s.userStore.GetUserByID(ctx, strconv.Itoa(session.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if user.Banned() {
+		return nil, ErrUserBanned
+	}
+
+	next, err := s.issueRefreshToken(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{ID: session.ID, User: user, RefreshToken: next}, nil
+}
+
+// EndSession revokes the session a refresh token belongs to. Access tokens
+// issued under the session stop being accepted immediately.
+func (s *Service) EndSession(ctx context.Context, refreshToken string) error {
+	_, session, err := s.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return s.sessionStore.RevokeSession(ctx, session.ID)
+}
+
+// IsSessionRevoked reports whether access tokens issued under sessionID must
+// be rejected. Unknown sessions count as revoked.
+func (s *Service) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	session, err := s.sessionStore.GetSessionByID(ctx, sessionID)
+	if errors.Is(err, sessions.ErrSessionNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return session.RevokedAt != nil, nil
+}
+
+// lookupRefreshToken finds a refresh token and its session, rejecting tokens
+// of revoked sessions
+func (s *Service) lookupRefreshToken(ctx context.Context, refreshToken string) (*model.RefreshToken, *model.Session, error) {
+	if refreshToken == "" {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	token, err := s.sessionStore.GetRefreshTokenByHash(ctx, secrets.Hash(refreshToken))
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionStore.GetSessionByID(ctx, token.SessionID)
+	if err != nil || session.RevokedAt != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	return token, session, nil
+}
+
+// revokeReusedSession revokes a session whose refresh token was presented
+// twice
+func (s *Service) revokeReusedSession(ctx context.Context, sessionID string) error {
+	if err := s.sessionStore.RevokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: token reuse detected, session revoked", ErrInvalidRefreshToken)
+}
+
+// issueRefreshToken creates and stores a new refresh token for a session
+func (s *Service) issueRefreshToken(ctx context.Context, sessionID string) (string, error) {
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.sessionStore.CreateRefreshToken(ctx, &model.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: secrets.Hash(refreshToken),
+		ExpiresAt: time.Now().Add(s.config.RefreshTokenTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// randomToken returns n random bytes encoded for use in URLs and headers
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -1,23 +1,39 @@
 package user_model
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
+	"github.com/ajlaz/checkmAIt/server/db/store/postgres/models"
 	"github.com/ajlaz/checkmAIt/server/model"
 )
 
-// CreateModel creates a new chess model
-func (s *Service) CreateModel(userID int, name, modelCode string) (*model.UserModel, error) {
+// CreateModel creates a new chess model playing the given variants, or
+// standard chess if none are given
+func (s *Service) CreateModel(ctx context.Context, userID int, name, modelCode string, variants []string, public bool) (*model.UserModel, error) {
 	// Validate the model code (you may want to add more specific validation)
 	if modelCode == "" {
 		return nil, errors.New("model code cannot be empty")
 	}
 
+	if err := validateVariants(variants); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCodeSize(modelCode); err != nil {
+		return nil, err
+	}
+
 	// Create a new model with default values
-	newModel := model.NewUserModel(userID, name, modelCode)
+	newModel := model.NewUserModel(userID, name, modelCode, variants, public)
 
-	// Save the model to the database
-	createdModel, err := s.modelStore.CreateModel(newModel)
+	// Save the model to the database, checking the model limit in the same
+	// transaction
+	createdModel, err := s.modelStore.CreateModel(ctx, newModel, s.quotas.MaxModels)
+	if errors.Is(err, models.ErrModelLimitReached) {
+		return nil, fmt.Errorf("%w: users may own up to %d models", ErrModelLimitReached, s.quotas.MaxModels)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -26,12 +42,12 @@ func (s *Service) CreateModel(userID int, name, modelCode string) (*model.UserMo
 }
 
 // GetModelByID retrieves a model by its ID
-func (s *Service) GetModelByID(modelID int) (*model.UserModel, error) {
+func (s *Service) GetModelByID(ctx context.Context, modelID int) (*model.UserModel, error) {
 	if modelID == 0 {
 		return nil, errors.New("model ID cannot be empty")
 	}
 
-	model, err := s.modelStore.GetModelByID(modelID)
+	model, err := s.modelStore.GetModelByID(ctx, modelID)
 	if err != nil {
 		return nil, err
 	}
@@ -40,12 +56,12 @@ func (s *Service) GetModelByID(modelID int) (*model.UserModel, error) {
 }
 
 // GetModelsByUserID retrieves all models for a specific user
-func (s *Service) GetModelsByUserID(userID int) ([]*model.UserModel, error) {
+func (s *Service) GetModelsByUserID(ctx context.Context, userID int) ([]*model.UserModel, error) {
 	if userID == 0 {
 		return nil, errors.New("user ID cannot be empty")
 	}
 
-	models, err := s.modelStore.GetModelsByUserID(userID)
+	models, err := s.modelStore.GetModelsByUserID(ctx, userID)
 	if err != nil {
 		// If there's a database error, propagate it
 		return nil, err
@@ -55,14 +71,23 @@ func (s *Service) GetModelsByUserID(userID int) ([]*model.UserModel, error) {
 	return models, nil
 }
 
-// UpdateModel updates an existing model
-func (s *Service) UpdateModel(modelID int, name, modelCode string) (*model.UserModel, error) {
+// UpdateModel updates an existing model. Empty fields and nil variants and
+// public are left unchanged.
+func (s *Service) UpdateModel(ctx context.Context, modelID int, name, modelCode string, variants []string, public *bool) (*model.UserModel, error) {
 	if modelID == 0 {
 		return nil, errors.New("model ID cannot be empty")
 	}
 
+	if err := validateVariants(variants); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCodeSize(modelCode); err != nil {
+		return nil, err
+	}
+
 	// Check if model exists
-	existingModel, err := s.modelStore.GetModelByID(modelID)
+	existingModel, err := s.modelStore.GetModelByID(ctx, modelID)
 	if err != nil {
 		return nil, err
 	}
@@ -74,9 +99,15 @@ func (s *Service) UpdateModel(modelID int, name, modelCode string) (*model.UserM
 	if modelCode != "" {
 		existingModel.Model = modelCode
 	}
+	if len(variants) > 0 {
+		existingModel.Variants = variants
+	}
+	if public != nil {
+		existingModel.Public = *public
+	}
 
 	// Save the updated model
-	updatedModel, err := s.modelStore.UpdateModel(existingModel)
+	updatedModel, err := s.modelStore.UpdateModel(ctx, existingModel)
 	if err != nil {
 		return nil, err
 	}
@@ -85,10 +116,28 @@ func (s *Service) UpdateModel(modelID int, name, modelCode string) (*model.UserM
 }
 
 // DeleteModel deletes a model by ID
-func (s *Service) DeleteModel(modelID int) error {
+func (s *Service) DeleteModel(ctx context.Context, modelID int) error {
 	if modelID == 0 {
 		return errors.New("model ID cannot be empty")
 	}
 
-	return s.modelStore.DeleteModel(modelID)
+	return s.modelStore.DeleteModel(ctx, modelID)
+}
+
+// checkCodeSize rejects model code larger than the quota allows
+func (s *Service) checkCodeSize(modelCode string) error {
+	if s.quotas.MaxCodeBytes > 0 && len(modelCode) > s.quotas.MaxCodeBytes {
+		return fmt.Errorf("%w: model code may be up to %d bytes", ErrCodeTooLarge, s.quotas.MaxCodeBytes)
+	}
+	return nil
+}
+
+// validateVariants checks that every declared variant is supported
+func validateVariants(variants []string) error {
+	for _, variant := range variants {
+		if !model.ValidVariant(variant) {
+			return fmt.Errorf("unsupported variant: %s", variant)
+		}
+	}
+	return nil
 }
@@ -1,6 +1,8 @@
 package user_model
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/ajlaz/checkmAIt/server/model"
@@ -8,14 +10,14 @@ import (
 
 // UpdateRating updates the ratings of two models after a match
 // where one model wins and the other loses
-func (s *Service) UpdateRating(winnerID, loserID int) (*model.UserModel, *model.UserModel, error) {
+func (s *Service) UpdateRating(ctx context.Context, winnerID, loserID int) (*model.UserModel, *model.UserModel, error) {
 	// Get current ratings
-	winner, err := s.modelStore.GetModelByID(winnerID)
+	winner, err := s.modelStore.GetModelByID(ctx, winnerID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get winner model: %w", err)
 	}
 
-	loser, err := s.modelStore.GetModelByID(loserID)
+	loser, err := s.modelStore.GetModelByID(ctx, loserID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get loser model: %w", err)
 	}
@@ -25,14 +27,14 @@ func (s *Service) UpdateRating(winnerID, loserID int) (*model.UserModel, *model.
 
 	// Update winner rating
 	winner.Rating = newWinnerRating
-	updatedWinner, err := s.modelStore.UpdateModel(winner)
+	updatedWinner, err := s.modelStore.UpdateModel(ctx, winner)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to update winner rating: %w", err)
 	}
 
 	// Update loser rating
 	loser.Rating = newLoserRating
-	updatedLoser, err := s.modelStore.UpdateModel(loser)
+	updatedLoser, err := s.modelStore.UpdateModel(ctx, loser)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to update loser rating: %w", err)
 	}
@@ -41,14 +43,14 @@ func (s *Service) UpdateRating(winnerID, loserID int) (*model.UserModel, *model.
 }
 
 // UpdateRatingDraw updates the ratings of two models after a draw match
-func (s *Service) UpdateRatingDraw(modelAID, modelBID int) (*model.UserModel, *model.UserModel, error) {
+func (s *Service) UpdateRatingDraw(ctx context.Context, modelAID, modelBID int) (*model.UserModel, *model.UserModel, error) {
 	// Get current ratings
-	modelA, err := s.modelStore.GetModelByID(modelAID)
+	modelA, err := s.modelStore.GetModelByID(ctx, modelAID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get model A: %w", err)
 	}
 
-	modelB, err := s.modelStore.GetModelByID(modelBID)
+	modelB, err := s.modelStore.GetModelByID(ctx, modelBID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get model B: %w", err)
 	}
@@ -58,17 +60,99 @@ func (s *Service) UpdateRatingDraw(modelAID, modelBID int) (*model.UserModel, *m
 
 	// Update model A rating
 	modelA.Rating = newModelARating
-	updatedModelA, err := s.modelStore.UpdateModel(modelA)
+	updatedModelA, err := s.modelStore.UpdateModel(ctx, modelA)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to update model A rating: %w", err)
 	}
 
 	// Update model B rating
 	modelB.Rating = newModelBRating
-	updatedModelB, err := s.modelStore.UpdateModel(modelB)
+	updatedModelB, err := s.modelStore.UpdateModel(ctx, modelB)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to update model B rating: %w", err)
 	}
 
 	return updatedModelA, updatedModelB, nil
 }
+
+// UpdateVariantRating updates the ratings of two models in a variant after
+// one beat the other. Standard chess ratings are kept on the model itself.
+func (s *Service) UpdateVariantRating(ctx context.Context, variant string, winnerID, loserID int) error {
+	if variant == model.VariantStandard {
+		_, _, err := s.UpdateRating(ctx, winnerID, loserID)
+		return err
+	}
+
+	winnerRating, loserRating, err := s.variantRatings(ctx, variant, winnerID, loserID)
+	if err != nil {
+		return err
+	}
+
+	newWinnerRating, newLoserRating := CalculateELO(winnerRating, loserRating)
+	return s.setVariantRatings(ctx, variant, winnerID, newWinnerRating, loserID, newLoserRating)
+}
+
+// UpdateVariantRatingDraw updates the ratings of two models in a variant
+// after a draw
+func (s *Service) UpdateVariantRatingDraw(ctx context.Context, variant string, modelAID, modelBID int) error {
+	if variant == model.VariantStandard {
+		_, _, err := s.UpdateRatingDraw(ctx, modelAID, modelBID)
+		return err
+	}
+
+	modelARating, modelBRating, err := s.variantRatings(ctx, variant, modelAID, modelBID)
+	if err != nil {
+		return err
+	}
+
+	newModelARating, newModelBRating := CalculateELODraw(modelARating, modelBRating)
+	return s.setVariantRatings(ctx, variant, modelAID, newModelARating, modelBID, newModelBRating)
+}
+
+// GetVariantRatings retrieves a model's ratings in variants other than
+// standard chess
+func (s *Service) GetVariantRatings(ctx context.Context, modelID int) ([]*model.VariantRating, error) {
+	if modelID == 0 {
+		return nil, errors.New("model ID cannot be empty")
+	}
+
+	return s.modelStore.GetVariantRatings(ctx, modelID)
+}
+
+// ResetRating puts a model back at the default rating in standard chess
+// and every variant
+func (s *Service) ResetRating(ctx context.Context, modelID int) error {
+	if modelID == 0 {
+		return errors.New("model ID cannot be empty")
+	}
+
+	return s.modelStore.ResetRatings(ctx, modelID)
+}
+
+// variantRatings gets the current ratings of two models in a variant
+func (s *Service) variantRatings(ctx context.Context, variant string, modelAID, modelBID int) (int, int, error) {
+	ratingA, err := s.modelStore.GetVariantRating(ctx, modelAID, variant)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get model %d rating: %w", modelAID, err)
+	}
+
+	ratingB, err := s.modelStore.GetVariantRating(ctx, modelBID, variant)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get model %d rating: %w", modelBID, err)
+	}
+
+	return ratingA, ratingB, nil
+}
+
+// setVariantRatings stores the new ratings of two models in a variant
+func (s *Service) setVariantRatings(ctx context.Context, variant string, modelAID, ratingA, modelBID, ratingB int) error {
+	if err := s.modelStore.SetVariantRating(ctx, modelAID, variant, ratingA); err != nil {
+		return fmt.Errorf("failed to update model %d rating: %w", modelAID, err)
+	}
+
+	if err := s.modelStore.SetVariantRating(ctx, modelBID, variant, ratingB); err != nil {
+		return fmt.Errorf("failed to update model %d rating: %w", modelBID, err)
+	}
+
+	return nil
+}
@@ -1,29 +1,51 @@
 package user_model
 
 import (
+	"context"
+	"errors"
+
 	"github.com/ajlaz/checkmAIt/server/db/store/postgres/models"
 	"github.com/ajlaz/checkmAIt/server/model"
 )
 
 // ServiceInterface defines the contract for user model service
 type ServiceInterface interface {
-	CreateModel(userID int, name, modelCode string) (*model.UserModel, error)
-	GetModelByID(modelID int) (*model.UserModel, error)
-	GetModelsByUserID(userID int) ([]*model.UserModel, error)
-	UpdateModel(modelID int, name, modelCode string) (*model.UserModel, error)
-	DeleteModel(modelID int) error
-	UpdateRating(winnerID, loserID int) (*model.UserModel, *model.UserModel, error)
-	UpdateRatingDraw(modelAID, modelBID int) (*model.UserModel, *model.UserModel, error)
+	CreateModel(ctx context.Context, userID int, name, modelCode string, variants []string, public bool) (*model.UserModel, error)
+	GetModelByID(ctx context.Context, modelID int) (*model.UserModel, error)
+	GetModelsByUserID(ctx context.Context, userID int) ([]*model.UserModel, error)
+	UpdateModel(ctx context.Context, modelID int, name, modelCode string, variants []string, public *bool) (*model.UserModel, error)
+	DeleteModel(ctx context.Context, modelID int) error
+	UpdateRating(ctx context.Context, winnerID, loserID int) (*model.UserModel, *model.UserModel, error)
+	UpdateRatingDraw(ctx context.Context, modelAID, modelBID int) (*model.UserModel, *model.UserModel, error)
+	UpdateVariantRating(ctx context.Context, variant string, winnerID, loserID int) error
+	UpdateVariantRatingDraw(ctx context.Context, variant string, modelAID, modelBID int) error
+	GetVariantRatings(ctx context.Context, modelID int) ([]*model.VariantRating, error)
+	ResetRating(ctx context.Context, modelID int) error
+}
+
+// ErrModelLimitReached is returned when a user already owns as many models
+// as Quotas allow
+var ErrModelLimitReached = errors.New("model limit reached")
+
+// ErrCodeTooLarge is returned when model code exceeds the size Quotas allow
+var ErrCodeTooLarge = errors.New("model code is too large")
+
+// Quotas limits what each user may store. Zero values disable a limit.
+type Quotas struct {
+	MaxModels    int // Models each user may own
+	MaxCodeBytes int // Largest model code accepted
 }
 
 // Service implements the user model service
 type Service struct {
 	modelStore models.StoreInterface
+	quotas     Quotas
 }
 
 // NewService creates a new model service instance
-func NewService(modelStore models.StoreInterface) ServiceInterface {
+func NewService(modelStore models.StoreInterface, quotas Quotas) ServiceInterface {
 	return &Service{
 		modelStore: modelStore,
+		quotas:     quotas,
 	}
 }